@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"todolist-api/pkg/config"
+)
+
+// runConfig 处理 config 子命令：
+//
+//	server config print [--redacted=false]  输出最终生效的配置，默认隐藏密钥
+//	server config validate                  只校验配置
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: server config <print|validate> [flags]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", "configs", "directory containing config.yml")
+	redacted := fs.Bool("redacted", true, "hide passwords, secrets and credentials in connection URLs")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	err := config.LoadConfig(*dir)
+	if config.Cfg == nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "print":
+		if printErr := config.Print(os.Stdout, *redacted); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr)
+			return 1
+		}
+	case "validate":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n", args[0])
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "config is valid")
+	return 0
+}
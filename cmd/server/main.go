@@ -1,21 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"todolist-api/internal/database"
 	"todolist-api/internal/handlers"
+	"todolist-api/internal/metrics"
 	"todolist-api/internal/middleware"
+	"todolist-api/internal/ratelimit"
 	"todolist-api/internal/repository"
 	"todolist-api/internal/routes"
+	"todolist-api/internal/server"
 	"todolist-api/internal/services"
+	"todolist-api/internal/tracing"
+	"todolist-api/pkg/blobstore"
 	"todolist-api/pkg/config"
+	"todolist-api/pkg/logger"
+	"todolist-api/pkg/mailer"
 
 	_ "todolist-api/docs"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // @title           Todo List API
@@ -36,39 +51,269 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description Type "Bearer" followed by a space and JWT token.
+// @description Type "Bearer" followed by a space and a JWT or personal access token.
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
 	if err := config.LoadConfig("configs"); err != nil {
-		log.Fatalf("could not load config: %v", err)
+		fatal("could not load config", err)
+	}
+	appLogger, err := logger.New(&config.Cfg.Log)
+	if err != nil {
+		fatal("could not create logger", err)
 	}
+	slog.SetDefault(appLogger)
+	config.LogLevel.Subscribe(func(level string) {
+		if err := logger.SetLevel(level); err != nil {
+			slog.Error("could not change log level", slog.Any("error", err))
+		}
+	})
+	shutdownTracing, err := tracing.Setup(context.Background(), &config.Cfg.Tracing)
+	if err != nil {
+		fatal("could not set up tracing", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
 
 	// 需要在初始化路由之前
 	db, err := database.Connect()
 	if err != nil {
-		log.Fatalf("cloud not connect db %v", err)
+		fatal("could not connect db", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		fatal("could not register db tracing", err)
+	}
+	replicas, err := database.UseReplicas(db, &config.Cfg.Database)
+	if err != nil {
+		fatal("could not set up read replicas", err)
 	}
 	// 初始化依赖
 	todoRepository := repository.NewTodoRepository(db)
-	todoHandler := handlers.NewTodoHandler(todoRepository)
-	authService := services.NewAuthService(&config.Cfg.JWT)
-	userHandler := handlers.NewUserHandler(todoRepository, authService)
+	projectHandler := handlers.NewProjectHandler(repository.NewProjectRepository(db),
+		repository.NewWorkflowRepository(db), todoRepository)
+	keySet, err := services.LoadKeySet(&config.Cfg.JWT)
+	if err != nil {
+		fatal("could not load jwt keys", err)
+	}
+	authService := services.NewAuthService(&config.Cfg.JWT, keySet, todoRepository)
+	recoveryCodeRepository := repository.NewRecoveryCodeRepository(db)
+	passwordResetRepository := repository.NewPasswordResetRepository(db)
+	mail, err := mailer.New(&config.Cfg.Mail)
+	if err != nil {
+		fatal("could not create mailer", err)
+	}
+	if config.Cfg.Mail.Driver == "log" {
+		slog.Warn("mail.driver is log, mail is not delivered; configure smtp outside development")
+	}
+	// 请求中的邮件在后台发送，关闭时等待发送中的邮件
+	asyncMail := mailer.NewAsync(mail, time.Duration(config.Cfg.Mail.TimeoutSeconds)*time.Second)
+	notifier := services.NewNotifier(todoRepository, asyncMail)
+	auditRepository := repository.NewAuditRepository(db)
+	auditLog := services.NewAuditLog(auditRepository)
+	undoRepository := repository.NewUndoRepository(db)
+	todoHandler := handlers.NewTodoHandler(todoRepository, notifier, auditLog, undoRepository, &config.Cfg.Todos)
+	undoHandler := handlers.NewUndoHandler(undoRepository, todoRepository, auditLog)
+	auditHandler := handlers.NewAuditHandler(auditRepository, todoRepository)
+	commentHandler := handlers.NewCommentHandler(repository.NewCommentRepository(db), todoRepository, notifier)
+	blobs, err := blobstore.New(context.Background(), &config.Cfg.Attachments)
+	if err != nil {
+		fatal("could not create attachment storage", err)
+	}
+	attachmentRepository := repository.NewAttachmentRepository(db)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentRepository, todoRepository, blobs,
+		&config.Cfg.Attachments)
+	userHandler := handlers.NewUserHandler(todoRepository, recoveryCodeRepository, passwordResetRepository,
+		authService, asyncMail, &config.Cfg.Password, auditLog)
+	personalTokenRepository := repository.NewPersonalTokenRepository(db)
+	personalTokenService := services.NewPersonalTokenService(personalTokenRepository)
+	tokenHandler := handlers.NewTokenHandler(personalTokenRepository, personalTokenService, auditLog)
+	wellKnownHandler := handlers.NewWellKnownHandler(authService)
+	healthHandler := handlers.NewHealthHandler(
+		handlers.HealthCheck{Name: "database", Check: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+		handlers.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+			return database.CheckMigrations(ctx, db)
+		}},
+	)
+	var oidcHandler *handlers.OIDCHandler
+	if config.Cfg.OIDC.Enabled {
+		oidcService := services.NewOIDCService(&config.Cfg.OIDC, []byte(config.Cfg.OIDC.StateSecret),
+			repository.NewIdentityRepository(db))
+		oidcHandler = handlers.NewOIDCHandler(oidcService, authService, auditLog)
+	}
+	var limiter *ratelimit.Limiter
+	if config.Cfg.RateLimit.Enabled {
+		store, err := ratelimit.NewStore(&config.Cfg.RateLimit, &config.Cfg.Redis)
+		if err != nil {
+			fatal("could not create rate limit store", err)
+		}
+		limiter = ratelimit.NewLimiter(store, &config.Cfg.RateLimit)
+		config.RateLimitGroups.Subscribe(limiter.SetRules)
+	}
+	// 订阅完成后再开始监听配置文件
+	config.Watch()
 	//r := gin.Default()
 	// 注册中间件
 	r := gin.New()
+	// 从 traceparent 请求头继续上游的链路，需要放在最前面，后面的日志才能带上 trace ID
+	r.Use(otelgin.Middleware(tracing.InstrumentationName))
+	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
 	r.Use(middleware.ErrorHandler())
+	if config.Cfg.SecurityHeaders.Enabled {
+		r.Use(middleware.SecurityHeaders(&config.Cfg.SecurityHeaders))
+	}
+	// 需要在限流和认证之前，预检请求不带令牌
+	if config.Cfg.CORS.Enabled {
+		r.Use(middleware.CORS(&config.Cfg.CORS))
+	}
+	if config.Cfg.Metrics.Enabled {
+		if err := metrics.RegisterDB(db, config.Cfg.Database.DBName); err != nil {
+			fatal("could not register db metrics", err)
+		}
+		r.Use(middleware.Metrics())
+	}
 
 	// 设置Swagger路由
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET(middleware.SwaggerPathPrefix+"*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// 设置路由
-	routes.SetupRoutes(r, todoHandler, userHandler, authService)
+	routes.SetupRoutes(r, todoHandler, commentHandler, attachmentHandler, auditHandler, undoHandler, projectHandler,
+		userHandler, tokenHandler, oidcHandler, wellKnownHandler, healthHandler, authService, personalTokenService,
+		&config.Cfg.Admin, limiter)
 
-	serverAddr := fmt.Sprintf(":%v", config.Cfg.Server.Port)
-	log.Printf("Server is running on port %v", config.Cfg.Server.Port)
-	log.Printf("Swagger documentation available at: http://localhost%v/swagger/index.html", serverAddr)
-	if err := r.Run(serverAddr); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	// 后台任务，关闭时统一停止
+	workers := server.NewWorkers()
+	var metricsServer *http.Server
+	// metricsErr 独立指标端口监听失败时通知主流程正常关闭
+	metricsErr := make(chan error, 1)
+	if config.Cfg.Metrics.Enabled {
+		metricsServer = serveMetrics(r, &config.Cfg.Metrics, workers, metricsErr)
+	}
+	if replicas != nil {
+		workers.Go(func(ctx context.Context) {
+			replicas.Monitor(ctx, time.Duration(config.Cfg.Database.ReplicaCheckSeconds)*time.Second)
+		})
+	}
+	if todosCfg := config.Cfg.Todos; todosCfg.PurgeAfterHours > 0 {
+		purger := services.NewTodoPurger(todoRepository, attachmentRepository, blobs)
+		workers.Go(func(ctx context.Context) {
+			purger.Run(ctx, time.Duration(todosCfg.PurgeIntervalMinutes)*time.Minute,
+				time.Duration(todosCfg.PurgeAfterHours)*time.Hour)
+		})
 	}
+	if auditCfg := config.Cfg.Audit; auditCfg.RetentionDays > 0 {
+		workers.Go(func(ctx context.Context) {
+			auditLog.Run(ctx, time.Duration(auditCfg.CleanupIntervalMinutes)*time.Minute,
+				time.Duration(auditCfg.RetentionDays)*24*time.Hour)
+		})
+	}
+
+	serverCfg := &config.Cfg.Server
+	srv := server.New(serverCfg, r)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe(srv, serverCfg)
+	}()
+	slog.Info("server is running", slog.Int("port", serverCfg.Port), slog.Bool("tls", serverCfg.TLSCertFile != ""),
+		slog.String("swagger", fmt.Sprintf("http://localhost:%d/swagger/index.html", serverCfg.Port)))
+
+	// 等待退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("failed to run server", err)
+		}
+	case err := <-metricsErr:
+		slog.Error("failed to run metrics server", slog.Any("error", err))
+		exitCode = 1
+	case <-ctx.Done():
+	}
+	// 再次收到信号时按默认行为立即退出
+	stop()
+
+	slog.Info("shutting down")
+	healthHandler.SetShuttingDown()
+	time.Sleep(time.Duration(serverCfg.ShutdownDelaySeconds) * time.Second)
+
+	timeout := serverCfg.ShutdownTimeoutSeconds
+	if timeout <= 0 {
+		timeout = 20
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	// 停止接受新连接并等待进行中的请求完成
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to drain http server", slog.Any("error", err))
+		_ = srv.Close()
+	}
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(shutdownCtx)
+	}
+	if err := asyncMail.Wait(shutdownCtx); err != nil {
+		slog.Error("failed to send pending mail", slog.Any("error", err))
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		slog.Error("failed to stop background workers", slog.Any("error", err))
+	}
+	if limiter != nil {
+		if err := limiter.Close(); err != nil {
+			slog.Error("failed to close rate limit store", slog.Any("error", err))
+		}
+	}
+	if replicas != nil {
+		if err := replicas.Close(); err != nil {
+			slog.Error("failed to close read replicas", slog.Any("error", err))
+		}
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("failed to close database", slog.Any("error", err))
+		}
+	}
+	slog.Info("server stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// serveMetrics 输出 Prometheus 指标，配置了独立端口时单独监听并返回该 server，否则挂在 API 路由上；
+// 监听失败时把错误发到 errs，由调用方决定如何关闭
+func serveMetrics(r *gin.Engine, cfg *config.MetricsConfig, workers *server.Workers, errs chan<- error) *http.Server {
+	path := cfg.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	if cfg.Port == 0 {
+		r.GET(path, gin.WrapH(metrics.Handler()))
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	slog.Info("metrics server is running", slog.Int("port", cfg.Port), slog.String("path", path))
+	workers.Go(func(context.Context) {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	})
+	return srv
+}
+
+// fatal 记录错误日志后退出
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
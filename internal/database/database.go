@@ -1,10 +1,17 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 	"todolist-api/internal/models"
 	"todolist-api/pkg/config"
+	"todolist-api/pkg/logger"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -12,27 +19,142 @@ import (
 
 //var DB *gorm.DB
 
+// Models 需要自动迁移的所有模型
+var Models = []any{&models.User{}, &models.Todo{}, &models.RecoveryCode{}, &models.PasswordResetToken{},
+	&models.PersonalAccessToken{}, &models.UserIdentity{}, &models.Project{}, &models.ProjectMember{},
+	&models.Comment{}, &models.CommentMention{}, &models.Attachment{}, &models.AuditEvent{},
+	&models.UndoToken{}, &models.WorkflowState{}, &models.TodoStateTransition{}, &models.TodoDependency{}}
+
 func Connect() (*gorm.DB, error) {
-	var err error
 	cfg := config.Cfg.Database
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=UTC",
-		cfg.Host,
-		cfg.User,
-		cfg.Password,
-		cfg.DBName,
-		cfg.Port,
-		cfg.SSLMode,
-	)
-	log.Println(dsn)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	logCfg := config.Cfg.Log
+	gormLogger, err := logger.NewGormLogger(slog.Default(), logCfg.SQLLevel, time.Duration(logCfg.SlowQueryMs)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	gormCfg := &gorm.Config{
+		// 将唯一约束冲突等数据库错误转换为 gorm.ErrDuplicatedKey 等通用错误
+		TranslateError: true,
+		Logger:         gormLogger,
+		// 缓存预编译语句，减少重复解析 SQL 的开销
+		PrepareStmt: cfg.PrepareStmt,
+	}
+	db, err := openWithRetry(BuildDSN(&cfg), gormCfg, cfg.ConnectRetries, time.Duration(cfg.ConnectBackoffMs)*time.Millisecond)
 	if err != nil {
 		return nil, err
 	}
-	if err = db.AutoMigrate(&models.User{}, &models.Todo{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	sqlDB, err := db.DB()
+	if err != nil {
 		return nil, err
 	}
-	log.Println("Database migrated successfully")
+	ConfigurePool(sqlDB, &cfg)
+
+	if err = db.AutoMigrate(Models...); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	slog.Info("database migrated successfully")
 	return db, nil
 }
+
+// BuildDSN 优先使用完整的连接地址（url），否则由各个字段拼接，并附加语句超时参数
+func BuildDSN(cfg *config.DatabaseConfig) string {
+	dsn := cfg.URL
+	if dsn == "" {
+		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=UTC",
+			cfg.Host,
+			cfg.User,
+			cfg.Password,
+			cfg.DBName,
+			cfg.Port,
+			cfg.SSLMode,
+		)
+	}
+	if cfg.StatementTimeoutMs > 0 {
+		// pgx 会把不认识的连接参数作为会话参数发送给 Postgres
+		dsn = withParam(dsn, "statement_timeout", strconv.Itoa(cfg.StatementTimeoutMs))
+	}
+	return dsn
+}
+
+// withParam 给 URL 形式（postgres://...）或 key=value 形式的 DSN 添加参数
+func withParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " " + key + "=" + value
+}
+
+// ConfigurePool 设置连接池参数，0 表示使用 database/sql 的默认值
+func ConfigurePool(sqlDB *sql.DB, cfg *config.DatabaseConfig) {
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetimeSeconds > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeSeconds) * time.Second)
+	}
+	if cfg.ConnMaxIdleTimeSeconds > 0 {
+		sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeSeconds) * time.Second)
+	}
+}
+
+// maxConnectBackoff 重试间隔的上限
+const maxConnectBackoff = 30 * time.Second
+
+// openWithRetry 连接失败时按指数退避重试，用于数据库比应用晚启动的情况（例如 docker-compose）
+func openWithRetry(dsn string, gormCfg *gorm.Config, retries int, backoff time.Duration) (*gorm.DB, error) {
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(dsn), gormCfg)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("could not connect to database after %d attempts: %w", attempt+1, err)
+		}
+		slog.Warn("database is not available, retrying", slog.Int("attempt", attempt+1),
+			slog.Duration("backoff", backoff), slog.Any("error", err))
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// CheckMigrations 检查所有模型的表和列都已存在，用于发现数据库落后于当前版本（例如迁移失败或被回滚）的情况
+func CheckMigrations(ctx context.Context, db *gorm.DB) error {
+	migrator := db.WithContext(ctx).Migrator()
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table %s is missing", stmt.Schema.Table)
+		}
+		columns, err := migrator.ColumnTypes(model)
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]bool, len(columns))
+		for _, column := range columns {
+			existing[column.Name()] = true
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !existing[field.DBName] {
+				return fmt.Errorf("column %s.%s is missing", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	return nil
+}
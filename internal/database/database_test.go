@@ -0,0 +1,21 @@
+package database
+
+import (
+	"testing"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDSN(t *testing.T) {
+	cfg := &config.DatabaseConfig{Host: "db", Port: 5432, User: "u", Password: "p", DBName: "todo", SSLMode: "disable"}
+	assert.Equal(t, "host=db user=u password=p dbname=todo port=5432 sslmode=disable TimeZone=UTC", BuildDSN(cfg))
+
+	cfg.StatementTimeoutMs = 5000
+	assert.Equal(t, "host=db user=u password=p dbname=todo port=5432 sslmode=disable TimeZone=UTC statement_timeout=5000",
+		BuildDSN(cfg))
+
+	// 配置了 URL 时忽略其他字段
+	cfg.URL = "postgres://u:p@db:5432/todo?sslmode=disable"
+	assert.Equal(t, "postgres://u:p@db:5432/todo?sslmode=disable&statement_timeout=5000", BuildDSN(cfg))
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"todolist-api/internal/metrics"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/config"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaPingTimeout 单个副本健康检查的超时时间
+const replicaPingTimeout = 2 * time.Second
+
+// replica 一个只读副本及其健康状态
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReplicaSet 管理只读副本：记录每个副本的健康状态，查询时在健康的副本间轮询，全部不健康时回退到主库
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	byPool   map[gorm.ConnPool]*replica
+	next     atomic.Uint64
+}
+
+// UseReplicas 把 todos 表的只读查询路由到配置的副本。
+// 其他表（用户、令牌等）的查询都与认证相关，不能容忍复制延迟，始终使用主库。
+// 没有配置副本时返回 nil
+func UseReplicas(db *gorm.DB, cfg *config.DatabaseConfig) (*ReplicaSet, error) {
+	if len(cfg.Replicas) == 0 {
+		return nil, nil
+	}
+	primary, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	rs := &ReplicaSet{primary: primary, byPool: map[gorm.ConnPool]*replica{}}
+	for i, dsn := range cfg.Replicas {
+		replicaCfg := *cfg
+		replicaCfg.URL = dsn
+		sqlDB, err := sql.Open("pgx", BuildDSN(&replicaCfg))
+		if err != nil {
+			_ = rs.Close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		ConfigurePool(sqlDB, cfg)
+		r := &replica{name: fmt.Sprintf("replica-%d", i), db: sqlDB}
+		rs.replicas = append(rs.replicas, r)
+		rs.byPool[sqlDB] = r
+	}
+	rs.check(context.Background())
+	for _, r := range rs.replicas {
+		if !r.healthy.Load() {
+			slog.Warn("read replica is unreachable at startup, queries fall back to the primary", slog.String("replica", r.name))
+		}
+	}
+
+	// 主库也作为一个候选加入，dbresolver 在只有一个候选时不会调用 Policy，这样单个副本时也能回退
+	dialectors := make([]gorm.Dialector, 0, len(rs.replicas)+1)
+	for _, r := range rs.replicas {
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: r.db}))
+	}
+	dialectors = append(dialectors, postgres.New(postgres.Config{Conn: primary}))
+	err = db.Use(dbresolver.Register(dbresolver.Config{Replicas: dialectors, Policy: rs}, &models.Todo{}))
+	if err != nil {
+		_ = rs.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Resolve 实现 dbresolver.Policy，在健康的副本间轮询，没有健康的副本时使用主库
+func (rs *ReplicaSet) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	var primary gorm.ConnPool
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		if r, ok := rs.byPool[pool]; ok {
+			if r.healthy.Load() {
+				healthy = append(healthy, pool)
+			}
+		} else {
+			primary = pool
+		}
+	}
+	if len(healthy) == 0 {
+		return primary
+	}
+	return healthy[rs.next.Add(1)%uint64(len(healthy))]
+}
+
+// Monitor 定期检查副本的健康状态，直到 ctx 取消
+func (rs *ReplicaSet) Monitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rs.check(ctx)
+		}
+	}
+}
+
+func (rs *ReplicaSet) check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				slog.Info("read replica is healthy", slog.String("replica", r.name))
+			} else {
+				slog.Warn("read replica is unhealthy, queries fall back to other replicas or the primary",
+					slog.String("replica", r.name), slog.Any("error", err))
+			}
+		}
+		if healthy {
+			metrics.DBReplicaUp.WithLabelValues(r.name).Set(1)
+		} else {
+			metrics.DBReplicaUp.WithLabelValues(r.name).Set(0)
+		}
+	}
+}
+
+// Close 关闭所有副本的连接池
+func (rs *ReplicaSet) Close() error {
+	var errs []error
+	for _, r := range rs.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
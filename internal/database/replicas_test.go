@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestReplicaSetResolve(t *testing.T) {
+	open := func() *sql.DB {
+		db, err := sql.Open("pgx", "postgres://localhost:1/test")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+	primary := open()
+	a := &replica{name: "replica-0", db: open()}
+	b := &replica{name: "replica-1", db: open()}
+	rs := &ReplicaSet{primary: primary, replicas: []*replica{a, b},
+		byPool: map[gorm.ConnPool]*replica{a.db: a, b.db: b}}
+	pools := []gorm.ConnPool{a.db, b.db, primary}
+
+	// 没有健康的副本时回退到主库
+	assert.Same(t, primary, rs.Resolve(pools))
+
+	// 只把查询发给健康的副本
+	b.healthy.Store(true)
+	for range 4 {
+		assert.Same(t, b.db, rs.Resolve(pools))
+	}
+
+	// 多个健康副本之间轮询
+	a.healthy.Store(true)
+	seen := map[gorm.ConnPool]bool{}
+	for range 4 {
+		seen[rs.Resolve(pools)] = true
+	}
+	assert.Len(t, seen, 2)
+	assert.False(t, seen[primary])
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/pkg/blobstore"
+	"todolist-api/pkg/config"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+	"unicode"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead 请求体中除文件内容以外的 multipart 边界和表单字段的大小上限
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	attachments repository.AttachmentRepository
+	todos       repository.TodoRepository
+	blobs       blobstore.BlobStore
+	cfg         *config.AttachmentsConfig
+}
+
+func NewAttachmentHandler(attachments repository.AttachmentRepository, todos repository.TodoRepository,
+	blobs blobstore.BlobStore, cfg *config.AttachmentsConfig) *AttachmentHandler {
+	return &AttachmentHandler{attachments: attachments, todos: todos, blobs: blobs, cfg: cfg}
+}
+
+// todo 返回当前用户可以查看的待办事项的 ID，write 为 true 时要求有修改权限
+func (h *AttachmentHandler) todo(c *gin.Context, uid uint, write bool) (uint, bool) {
+	todoID, ok := parseID(c, "id")
+	if !ok {
+		return 0, false
+	}
+	todos := h.todos.WithContext(c.Request.Context())
+	var err error
+	if write {
+		err = todos.CheckWritable(uid, todoID)
+	} else {
+		_, err = todos.GetById(uid, todoID)
+	}
+	switch {
+	case err == nil:
+		return todoID, true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = c.Error(ierr.ErrTodoNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		_ = c.Error(ierr.ErrForbidden)
+	default:
+		_ = c.Error(ierr.ErrSystem)
+	}
+	return 0, false
+}
+
+// attachment 返回待办事项下的附件
+func (h *AttachmentHandler) attachment(c *gin.Context, todoID uint) (*models.Attachment, bool) {
+	id, ok := parseID(c, "aid")
+	if !ok {
+		return nil, false
+	}
+	attachment, err := h.attachments.WithContext(c.Request.Context()).GetById(todoID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrAttachmentNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return nil, false
+	}
+	return attachment, true
+}
+
+// sanitizeFilename 只保留文件名本身，去掉路径和控制字符
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+func newStorageKey(todoID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("todos/%d/%s", todoID, hex.EncodeToString(buf)), nil
+}
+
+// UploadAttachment godoc
+// @Summary      上传附件
+// @Description  以 multipart/form-data 上传文件（字段名 file），需要修改待办事项的权限。
+// @Description  文件类型按内容识别，只允许配置中的类型，大小不能超过配置的上限
+// @Tags         attachments
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id    path      int   true  "Todo ID"
+// @Param        file  formData  file  true  "附件"
+// @Success      200   {object}  models.Attachment
+// @Failure      400   {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401   {object}  map[string]interface{}  "未授权"
+// @Failure      403   {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404   {object}  map[string]interface{}  "Todo未找到"
+// @Failure      413   {object}  map[string]interface{}  "文件太大"
+// @Failure      415   {object}  map[string]interface{}  "不允许的文件类型"
+// @Router       /todos/{id}/attachments [post]
+// @Security    BearerAuth
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	todoID, ok := h.todo(c, uid.(uint), true)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxSizeBytes+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(ierr.ErrAttachmentTooLarge)
+			return
+		}
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if header.Size > h.cfg.MaxSizeBytes {
+		_ = c.Error(ierr.ErrAttachmentTooLarge)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	defer file.Close()
+	// 按文件内容识别类型，不使用客户端声明的 Content-Type
+	detected, err := mimetype.DetectReader(file)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	if !mimetype.EqualsAny(detected.String(), h.cfg.AllowedTypes...) {
+		_ = c.Error(ierr.ErrUnsupportedType.WithMsg(fmt.Sprintf("Attachment type %s is not allowed",
+			detected.String())))
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+
+	key, err := newStorageKey(todoID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.blobs.Put(ctx, key, file, header.Size, detected.String()); err != nil {
+		slog.ErrorContext(ctx, "failed to store attachment", slog.String("key", key), slog.Any("error", err))
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	attachment := models.Attachment{TodoId: todoID, UserId: uid.(uint), Filename: sanitizeFilename(header.Filename),
+		ContentType: detected.String(), Size: header.Size, StorageKey: key}
+	if err := h.attachments.WithContext(ctx).Create(&attachment); err != nil {
+		// 记录没有保存成功，删除已经上传的文件
+		if err := h.blobs.Delete(ctx, key); err != nil {
+			slog.ErrorContext(ctx, "failed to remove orphaned attachment", slog.String("key", key),
+				slog.Any("error", err))
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, attachment)
+}
+
+// ListAttachments godoc
+// @Summary      获取附件列表
+// @Description  按上传时间顺序返回待办事项的附件，需要有权查看该待办事项
+// @Tags         attachments
+// @Produce      json
+// @Param        id   path      int  true  "Todo ID"
+// @Success      200  {array}   models.Attachment
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/attachments [get]
+// @Security    BearerAuth
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	todoID, ok := h.todo(c, uid.(uint), false)
+	if !ok {
+		return
+	}
+	attachments, err := h.attachments.WithContext(c.Request.Context()).List(todoID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, attachments)
+}
+
+// DownloadAttachment godoc
+// @Summary      下载附件
+// @Description  返回附件的文件内容，以附件形式下载，需要有权查看该待办事项
+// @Tags         attachments
+// @Produce      octet-stream
+// @Param        id   path      int  true  "Todo ID"
+// @Param        aid  path      int  true  "附件ID"
+// @Success      200  {file}    file
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo或附件未找到"
+// @Router       /todos/{id}/attachments/{aid} [get]
+// @Security    BearerAuth
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	todoID, ok := h.todo(c, uid.(uint), false)
+	if !ok {
+		return
+	}
+	attachment, ok := h.attachment(c, todoID)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	reader, err := h.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read attachment", slog.String("key", attachment.StorageKey),
+			slog.Any("error", err))
+		if errors.Is(err, blobstore.ErrNotFound) {
+			_ = c.Error(ierr.ErrAttachmentNotFound)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	defer reader.Close()
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader,
+		map[string]string{"Content-Disposition": disposition})
+}
+
+// DeleteAttachment godoc
+// @Summary      删除附件
+// @Description  删除附件记录和文件，需要修改待办事项的权限
+// @Tags         attachments
+// @Produce      json
+// @Param        id   path      int  true  "Todo ID"
+// @Param        aid  path      int  true  "附件ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo或附件未找到"
+// @Router       /todos/{id}/attachments/{aid} [delete]
+// @Security    BearerAuth
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	todoID, ok := h.todo(c, uid.(uint), true)
+	if !ok {
+		return
+	}
+	attachment, ok := h.attachment(c, todoID)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.attachments.WithContext(ctx).Delete(attachment); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	// 记录已经删除，文件删除失败只会留下无法访问的文件，不影响结果
+	if err := h.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+		slog.ErrorContext(ctx, "failed to delete attachment file", slog.String("key", attachment.StorageKey),
+			slog.Any("error", err))
+	}
+	response.Success(c, nil)
+}
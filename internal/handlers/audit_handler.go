@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	events repository.AuditRepository
+	todos  repository.TodoRepository
+}
+
+func NewAuditHandler(events repository.AuditRepository, todos repository.TodoRepository) *AuditHandler {
+	return &AuditHandler{events: events, todos: todos}
+}
+
+// newAuditEvent 根据请求创建审计事件，操作者为当前登录的用户，before 和 after 用于计算变化的字段
+func newAuditEvent(c *gin.Context, action, entityType string, entityID uint, before, after any) *models.AuditEvent {
+	event := &models.AuditEvent{Action: action, EntityType: entityType, EntityId: entityID,
+		Changes: services.Diff(before, after), IP: c.ClientIP(), RequestId: c.GetString("request_id")}
+	if uid, exists := c.Get("uid"); exists {
+		actor := uid.(uint)
+		event.ActorId = &actor
+	}
+	return event
+}
+
+// AuditQuery 审计事件的过滤条件和分页参数
+type AuditQuery struct {
+	PageQuery
+	ActorId    *uint  `form:"actor_id" example:"1"`
+	Action     string `form:"action" example:"todo.delete"`
+	EntityType string `form:"entity_type" example:"todo"`
+	EntityId   *uint  `form:"entity_id" example:"1"`
+	// From、To RFC 3339 格式的时间范围，包含 from，不包含 to
+	From *time.Time `form:"from" example:"2024-01-01T00:00:00Z"`
+	To   *time.Time `form:"to" example:"2024-02-01T00:00:00Z"`
+}
+
+// AuditEventListResponse 一页审计事件
+type AuditEventListResponse struct {
+	Page
+	Events []models.AuditEvent `json:"events"`
+}
+
+func (h *AuditHandler) list(c *gin.Context, filter repository.AuditFilter, query PageQuery) {
+	events, total, err := h.events.WithContext(c.Request.Context()).List(filter, query.offset(), query.PageSize)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	response.Success(c, AuditEventListResponse{Page: newPage(query, total), Events: events})
+}
+
+// TodoHistory godoc
+// @Summary      获取Todo的修改历史
+// @Description  按时间倒序返回待办事项的审计事件，需要有权查看该待办事项。已删除但还没有彻底清除的待办事项也可以查看
+// @Tags         audit
+// @Produce      json
+// @Param        id         path      int  true   "Todo ID"
+// @Param        page       query     int  false  "页码，从 1 开始"
+// @Param        page_size  query     int  false  "每页数量，默认 20，最大 100"
+// @Success      200  {object}  AuditEventListResponse
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/history [get]
+// @Security    BearerAuth
+func (h *AuditHandler) TodoHistory(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	query.normalize()
+	todoID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if _, err := h.todos.WithContext(c.Request.Context()).GetIncludingDeleted(uid.(uint), todoID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrTodoNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return
+	}
+	h.list(c, repository.AuditFilter{EntityType: models.AuditEntityTodo, EntityId: &todoID}, query)
+}
+
+// ListAuditEvents godoc
+// @Summary      查询审计事件
+// @Description  按时间倒序返回所有审计事件，可以按操作者、操作、对象和时间范围过滤。只有管理员可以访问
+// @Tags         audit
+// @Produce      json
+// @Param        actor_id     query     int     false  "操作者的用户ID"
+// @Param        action       query     string  false  "操作，例如 todo.delete"
+// @Param        entity_type  query     string  false  "对象类型：todo 或 user"
+// @Param        entity_id    query     int     false  "对象ID"
+// @Param        from         query     string  false  "开始时间（RFC 3339），包含"
+// @Param        to           query     string  false  "结束时间（RFC 3339），不包含"
+// @Param        page         query     int     false  "页码，从 1 开始"
+// @Param        page_size    query     int     false  "每页数量，默认 20，最大 100"
+// @Success      200  {object}  AuditEventListResponse
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "不是管理员"
+// @Router       /admin/audit [get]
+// @Security    BearerAuth
+func (h *AuditHandler) ListAuditEvents(c *gin.Context) {
+	var query AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	query.normalize()
+	h.list(c, repository.AuditFilter{ActorId: query.ActorId, Action: query.Action, EntityType: query.EntityType,
+		EntityId: query.EntityId, From: query.From, To: query.To}, query.PageQuery)
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentHandler struct {
+	comments repository.CommentRepository
+	todos    repository.TodoRepository
+	notifier *services.Notifier
+}
+
+func NewCommentHandler(comments repository.CommentRepository, todos repository.TodoRepository,
+	notifier *services.Notifier) *CommentHandler {
+	return &CommentHandler{comments: comments, todos: todos, notifier: notifier}
+}
+
+// CommentInput 发表或修改评论的参数
+type CommentInput struct {
+	Body string `json:"body" binding:"required,max=5000" example:"@janedoe 记得买低脂的"`
+}
+
+// CommentResponse 评论及作者、提及的用户
+type CommentResponse struct {
+	ID       uint   `json:"id" example:"1"`
+	TodoId   uint   `json:"todo_id" example:"1"`
+	UserId   uint   `json:"uid" example:"1"`
+	Username string `json:"username" example:"johndoe"`
+	Body     string `json:"body" example:"@janedoe 记得买低脂的"`
+	Edited   bool   `json:"edited" example:"false"`
+	// Mentions 提及的用户名
+	Mentions  []string  `json:"mentions" example:"janedoe"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CommentListResponse 一页评论
+type CommentListResponse struct {
+	Page
+	Comments []CommentResponse `json:"comments"`
+}
+
+func newCommentResponse(comment *models.Comment) CommentResponse {
+	res := CommentResponse{ID: comment.ID, TodoId: comment.TodoId, UserId: comment.UserId, Body: comment.Body,
+		Edited: comment.Edited, Mentions: make([]string, 0, len(comment.Mentions)),
+		CreatedAt: comment.CreatedAt, UpdatedAt: comment.UpdatedAt}
+	if comment.Author != nil {
+		res.Username = comment.Author.Username
+	}
+	for _, m := range comment.Mentions {
+		if m.User != nil {
+			res.Mentions = append(res.Mentions, m.User.Username)
+		}
+	}
+	return res
+}
+
+// todo 返回当前用户可以查看的待办事项
+func (h *CommentHandler) todo(c *gin.Context, uid uint) (*models.Todo, bool) {
+	todoID, ok := parseID(c, "id")
+	if !ok {
+		return nil, false
+	}
+	todo, err := h.todos.WithContext(c.Request.Context()).GetById(uid, todoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrTodoNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return nil, false
+	}
+	return todo, true
+}
+
+// comment 返回待办事项下的评论
+func (h *CommentHandler) comment(c *gin.Context, todo *models.Todo) (*models.Comment, bool) {
+	commentID, ok := parseID(c, "cid")
+	if !ok {
+		return nil, false
+	}
+	comment, err := h.comments.WithContext(c.Request.Context()).GetById(todo.ID, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrCommentNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return nil, false
+	}
+	return comment, true
+}
+
+// mentions 解析评论中提及的用户，只保留有权查看该待办事项的用户
+func (h *CommentHandler) mentions(c *gin.Context, todo *models.Todo, body string) ([]models.User, error) {
+	return h.comments.WithContext(c.Request.Context()).MentionableUsers(todo, models.ParseMentions(body))
+}
+
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	return ids
+}
+
+// ListComments godoc
+// @Summary      获取评论列表
+// @Description  按发布时间顺序分页返回待办事项的评论，需要有权查看该待办事项
+// @Tags         comments
+// @Produce      json
+// @Param        id         path      int  true   "Todo ID"
+// @Param        page       query     int  false  "页码，从 1 开始"
+// @Param        page_size  query     int  false  "每页数量，默认 20，最大 100"
+// @Success      200  {object}  CommentListResponse
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/comments [get]
+// @Security    BearerAuth
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	query.normalize()
+	todo, ok := h.todo(c, uid.(uint))
+	if !ok {
+		return
+	}
+	comments, total, err := h.comments.WithContext(c.Request.Context()).List(todo.ID, query.offset(), query.PageSize)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	res := CommentListResponse{Page: newPage(query, total), Comments: make([]CommentResponse, 0, len(comments))}
+	for i := range comments {
+		res.Comments = append(res.Comments, newCommentResponse(&comments[i]))
+	}
+	response.Success(c, res)
+}
+
+// CreateComment godoc
+// @Summary      发表评论
+// @Description  有权查看待办事项的用户都可以发表评论。评论中用 @用户名 提及的用户如果有权查看该待办事项，会被记录并收到通知
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int           true  "Todo ID"
+// @Param        input  body      CommentInput  true  "评论内容"
+// @Success      200    {object}  CommentResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "未授权"
+// @Failure      404    {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/comments [post]
+// @Security    BearerAuth
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input CommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	todo, ok := h.todo(c, uid.(uint))
+	if !ok {
+		return
+	}
+	mentioned, err := h.mentions(c, todo, input.Body)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	repo := h.comments.WithContext(c.Request.Context())
+	comment := models.Comment{TodoId: todo.ID, UserId: uid.(uint), Body: input.Body}
+	if err := repo.Create(&comment, userIDs(mentioned)); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.notifier.Mentioned(c.Request.Context(), todo, &comment, userIDs(mentioned))
+	created, err := repo.GetById(todo.ID, comment.ID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, newCommentResponse(created))
+}
+
+// UpdateComment godoc
+// @Summary      修改评论
+// @Description  只有作者可以修改自己的评论，修改后标记为已编辑。新提及的用户会收到通知
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int           true  "Todo ID"
+// @Param        cid    path      int           true  "评论ID"
+// @Param        input  body      CommentInput  true  "评论内容"
+// @Success      200    {object}  CommentResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "未授权"
+// @Failure      403    {object}  map[string]interface{}  "不是评论的作者"
+// @Failure      404    {object}  map[string]interface{}  "Todo或评论未找到"
+// @Router       /todos/{id}/comments/{cid} [put]
+// @Security    BearerAuth
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input CommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	todo, ok := h.todo(c, uid.(uint))
+	if !ok {
+		return
+	}
+	comment, ok := h.comment(c, todo)
+	if !ok {
+		return
+	}
+	if comment.UserId != uid.(uint) {
+		_ = c.Error(ierr.ErrForbidden)
+		return
+	}
+	mentioned, err := h.mentions(c, todo, input.Body)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	// 之前已经提及过的用户不再重复通知
+	notified := make(map[uint]bool, len(comment.Mentions))
+	for _, m := range comment.Mentions {
+		notified[m.UserId] = true
+	}
+	var added []uint
+	for _, user := range mentioned {
+		if !notified[user.ID] {
+			added = append(added, user.ID)
+		}
+	}
+	repo := h.comments.WithContext(c.Request.Context())
+	if err := repo.Update(comment, input.Body, userIDs(mentioned)); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.notifier.Mentioned(c.Request.Context(), todo, comment, added)
+	updated, err := repo.GetById(todo.ID, comment.ID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, newCommentResponse(updated))
+}
+
+// DeleteComment godoc
+// @Summary      删除评论
+// @Description  作者可以删除自己的评论，有权修改该待办事项的用户可以删除任何评论
+// @Tags         comments
+// @Produce      json
+// @Param        id   path      int  true  "Todo ID"
+// @Param        cid  path      int  true  "评论ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "没有权限删除"
+// @Failure      404  {object}  map[string]interface{}  "Todo或评论未找到"
+// @Router       /todos/{id}/comments/{cid} [delete]
+// @Security    BearerAuth
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	todo, ok := h.todo(c, uid.(uint))
+	if !ok {
+		return
+	}
+	comment, ok := h.comment(c, todo)
+	if !ok {
+		return
+	}
+	if comment.UserId != uid.(uint) {
+		if err := h.todos.WithContext(c.Request.Context()).CheckWritable(uid.(uint), todo.ID); err != nil {
+			if errors.Is(err, repository.ErrForbidden) {
+				_ = c.Error(ierr.ErrForbidden)
+			} else {
+				_ = c.Error(ierr.ErrSystem)
+			}
+			return
+		}
+	}
+	if err := h.comments.WithContext(c.Request.Context()).Delete(comment); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, nil)
+}
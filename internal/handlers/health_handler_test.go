@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ready := func(t *testing.T, h *HealthHandler) (int, ReadinessResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		h.Readiness(c)
+		var res ReadinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.NotContains(t, w.Body.String(), "password")
+		return w.Code, res
+	}
+	up := HealthCheck{Name: "database", Check: func(context.Context) error { return nil }}
+	down := HealthCheck{Name: "migrations", Check: func(context.Context) error {
+		return errors.New("dial tcp postgres://app:password@db:5432: connection refused")
+	}}
+
+	t.Run("all checks up", func(t *testing.T) {
+		code, res := ready(t, NewHealthHandler(up))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", res.Status)
+		assert.Equal(t, "up", res.Checks["database"].Status)
+	})
+
+	t.Run("a failing check is reported without its error", func(t *testing.T) {
+		code, res := ready(t, NewHealthHandler(up, down))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not_ready", res.Status)
+		assert.Equal(t, "up", res.Checks["database"].Status)
+		assert.Equal(t, "down", res.Checks["migrations"].Status)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		h := NewHealthHandler(up)
+		h.SetShuttingDown()
+		code, res := ready(t, h)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "shutting_down", res.Status)
+		assert.Empty(t, res.Checks)
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"todolist-api/internal/models"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// oidcStateCookie 保存登录状态（state、nonce、PKCE verifier）的 cookie
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/v1/user/oidc"
+)
+
+type OIDCHandler struct {
+	service     *services.OIDCService
+	authService *services.AuthService
+	audit       *services.AuditLog
+}
+
+func NewOIDCHandler(service *services.OIDCService, authService *services.AuthService,
+	audit *services.AuditLog) *OIDCHandler {
+	return &OIDCHandler{service: service, authService: authService, audit: audit}
+}
+
+// Login godoc
+// @Summary      通过外部身份提供方登录
+// @Description  跳转到 OpenID Connect 身份提供方进行登录（授权码 + PKCE）
+// @Tags         users
+// @Success      302
+// @Failure      500  {object}  map[string]interface{}  "身份提供方不可用"
+// @Router       /user/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	url, state, err := h.service.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "oidc login failed", slog.Any("error", err))
+		_ = c.Error(ierr.ErrOIDCUnavailable)
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, oidcCookiePath, "", h.secureCookie(c), true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback godoc
+// @Summary      外部身份提供方登录回调
+// @Description  校验授权码和 ID Token，关联（首次登录时创建）本地用户并返回JWT令牌
+// @Tags         users
+// @Produce      json
+// @Param        code   query     string  true  "授权码"
+// @Param        state  query     string  true  "登录状态"
+// @Success      200    {object}  LoginResponse
+// @Failure      401    {object}  map[string]interface{}  "登录失败"
+// @Router       /user/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	state, _ := c.Cookie(oidcStateCookie)
+	// 状态只能使用一次
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", h.secureCookie(c), true)
+	if errParam := c.Query("error"); errParam != "" {
+		slog.WarnContext(c.Request.Context(), "oidc provider returned error",
+			slog.String("error", errParam), slog.String("description", c.Query("error_description")))
+		_ = c.Error(ierr.ErrOIDCLogin)
+		return
+	}
+	user, identity, err := h.service.Exchange(c.Request.Context(), c.Query("code"), c.Query("state"), state)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "oidc callback failed", slog.Any("error", err))
+		_ = c.Error(ierr.ErrOIDCLogin)
+		return
+	}
+	if identity != nil {
+		// 首次登录时自动创建了用户并关联外部身份，操作者就是该用户
+		event := newAuditEvent(c, models.AuditUserIdentityLink, models.AuditEntityUser, user.ID, nil, identity)
+		event.ActorId = &user.ID
+		h.audit.Record(c.Request.Context(), event)
+	}
+	token, err := h.authService.GenerateToken(user)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, token)
+}
+
+func (h *OIDCHandler) secureCookie(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
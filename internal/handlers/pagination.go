@@ -0,0 +1,38 @@
+package handlers
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// PageQuery 分页参数，page 从 1 开始
+type PageQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+// normalize 填充默认值
+func (q *PageQuery) normalize() {
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.PageSize == 0 {
+		q.PageSize = defaultPageSize
+	}
+	q.PageSize = min(q.PageSize, maxPageSize)
+}
+
+func (q *PageQuery) offset() int {
+	return (q.Page - 1) * q.PageSize
+}
+
+// Page 分页列表的公共字段
+type Page struct {
+	Total    int64 `json:"total" example:"42"`
+	Page     int   `json:"page" example:"1"`
+	PageSize int   `json:"page_size" example:"20"`
+}
+
+func newPage(q PageQuery, total int64) Page {
+	return Page{Total: total, Page: q.Page, PageSize: q.PageSize}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/mailer"
+	"todolist-api/pkg/password"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ChangePasswordInput 修改密码时需要提供旧密码
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password" binding:"required" example:"password123"`
+	NewPassword string `json:"new_password" binding:"required" example:"newPassword456"`
+}
+
+// ForgotPasswordInput 找回密码时提交的邮箱
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+// ResetPasswordInput 使用邮件中的令牌重置密码
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required" example:"3f2a...c9"`
+	NewPassword string `json:"new_password" binding:"required" example:"newPassword456"`
+}
+
+// ChangePassword godoc
+// @Summary      修改密码
+// @Description  验证旧密码后修改密码，之前签发的所有令牌随之失效，需要重新登录
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      ChangePasswordInput  true  "旧密码和新密码"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]interface{}  "新密码不符合密码策略"
+// @Failure      401    {object}  map[string]interface{}  "旧密码错误"
+// @Router       /user/password [post]
+// @Security    BearerAuth
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	user, err := h.repo.GetUserById(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrUserNotFound)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.OldPassword)); err != nil {
+		_ = c.Error(ierr.ErrInvalidCredentials)
+		return
+	}
+	if err := h.setPassword(user, input.NewPassword); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserPasswordChange, models.AuditEntityUser,
+		user.ID, nil, nil))
+	response.Success(c, nil)
+}
+
+// ForgotPassword godoc
+// @Summary      找回密码
+// @Description  向账户绑定的邮箱发送重置密码的链接。无论邮箱是否存在都返回成功，避免泄露账户信息
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      ForgotPasswordInput  true  "邮箱"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Router       /user/password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	user, err := h.repo.GetUserByEmail(input.Email)
+	if err != nil {
+		response.Success(c, nil)
+		return
+	}
+	token, err := h.issueResetToken(user)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserPasswordResetRequest,
+		models.AuditEntityUser, user.ID, nil, nil))
+	msg := mailer.Message{
+		To:      input.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires in %d minutes.\n\n%s\n\n"+
+			"If you did not request a password reset, you can ignore this email.\n",
+			user.Username, h.passwordCfg.ResetTokenTTLMinutes, h.resetLink(token)),
+	}
+	// 邮件在后台发送（见 mailer.Async），响应时间不会暴露邮箱是否存在
+	if err := h.mailer.Send(c.Request.Context(), msg); err != nil {
+		// 发送失败同样返回成功，避免通过响应区分邮箱是否存在
+		slog.ErrorContext(c.Request.Context(), "failed to send password reset mail",
+			slog.Uint64("target_user_id", uint64(user.ID)), slog.Any("error", err))
+	}
+	response.Success(c, nil)
+}
+
+// ResetPassword godoc
+// @Summary      重置密码
+// @Description  使用找回密码邮件中的一次性令牌设置新密码，之前签发的所有令牌随之失效
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      ResetPasswordInput  true  "重置令牌和新密码"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]interface{}  "令牌无效或新密码不符合密码策略"
+// @Router       /user/password/reset [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	token, err := h.resetRepo.GetValid(hashResetToken(input.Token))
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidResetToken)
+		return
+	}
+	user, err := h.repo.GetUserById(token.UserId)
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidResetToken)
+		return
+	}
+	// 先校验密码策略，不符合时令牌仍然可以继续使用
+	if err := password.Validate(h.passwordCfg, input.NewPassword, user.Username); err != nil {
+		_ = c.Error(ierr.ErrWeakPassword.WithMsg(err.Error()))
+		return
+	}
+	// 使用令牌和修改密码在同一个事务中完成，修改失败时令牌不会被消耗
+	if err := h.resetRepo.ResetPassword(token, input.NewPassword); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrInvalidResetToken)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserPasswordReset, models.AuditEntityUser,
+		user.ID, nil, nil))
+	response.Success(c, nil)
+}
+
+// setPassword 校验密码策略并保存新密码，同时作废已签发的令牌和未使用的重置令牌
+func (h *UserHandler) setPassword(user *models.User, newPassword string) error {
+	if err := password.Validate(h.passwordCfg, newPassword, user.Username); err != nil {
+		return ierr.ErrWeakPassword.WithMsg(err.Error())
+	}
+	if err := h.repo.UpdatePassword(user.ID, newPassword); err != nil {
+		return ierr.ErrSystem
+	}
+	if err := h.resetRepo.RevokeAll(user.ID); err != nil {
+		slog.Error("failed to revoke password reset tokens", slog.Uint64("target_user_id", uint64(user.ID)), slog.Any("error", err))
+	}
+	return nil
+}
+
+// issueResetToken 生成重置令牌，保存哈希并返回明文
+func (h *UserHandler) issueResetToken(user *models.User) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	ttl := time.Duration(h.passwordCfg.ResetTokenTTLMinutes) * time.Minute
+	err := h.resetRepo.Create(&models.PasswordResetToken{
+		UserId:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (h *UserHandler) resetLink(token string) string {
+	if h.passwordCfg.ResetURL == "" {
+		return token
+	}
+	u, err := url.Parse(h.passwordCfg.ResetURL)
+	if err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
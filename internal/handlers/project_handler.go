@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProjectHandler struct {
+	repo   repository.ProjectRepository
+	states repository.WorkflowRepository
+	users  repository.TodoRepository
+}
+
+func NewProjectHandler(repo repository.ProjectRepository, states repository.WorkflowRepository,
+	users repository.TodoRepository) *ProjectHandler {
+	return &ProjectHandler{repo: repo, states: states, users: users}
+}
+
+// CreateProjectInput 创建项目的参数
+type CreateProjectInput struct {
+	Name string `json:"name" binding:"required,max=100" example:"家庭采购"`
+}
+
+// InviteMemberInput 邀请成员的参数
+type InviteMemberInput struct {
+	Username string `json:"username" binding:"required" example:"janedoe"`
+	Role     string `json:"role" binding:"required,oneof=viewer editor owner" example:"editor"`
+}
+
+// UpdateMemberInput 修改成员角色的参数
+type UpdateMemberInput struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor owner" example:"viewer"`
+}
+
+// ProjectResponse 项目信息以及当前用户在项目中的角色
+type ProjectResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	Name      string    `json:"name" example:"家庭采购"`
+	UserId    uint      `json:"uid" example:"1"`
+	Role      string    `json:"role" example:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemberResponse 项目成员或待处理的邀请
+type MemberResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	UserId    uint      `json:"uid" example:"2"`
+	Username  string    `json:"username" example:"janedoe"`
+	Role      string    `json:"role" example:"editor"`
+	Status    string    `json:"status" example:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectDetailResponse 项目信息和成员列表
+type ProjectDetailResponse struct {
+	ProjectResponse
+	Members []MemberResponse `json:"members"`
+}
+
+// InvitationResponse 发给当前用户的邀请
+type InvitationResponse struct {
+	ID          uint      `json:"id" example:"3"`
+	ProjectId   uint      `json:"project_id" example:"1"`
+	ProjectName string    `json:"project_name" example:"家庭采购"`
+	Role        string    `json:"role" example:"editor"`
+	InvitedBy   uint      `json:"invited_by" example:"1"`
+	Status      string    `json:"status" example:"pending"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newProjectResponse(p *models.Project, role string) ProjectResponse {
+	return ProjectResponse{ID: p.ID, Name: p.Name, UserId: p.UserId, Role: role, CreatedAt: p.CreatedAt}
+}
+
+func newInvitationResponse(m *models.ProjectMember) InvitationResponse {
+	res := InvitationResponse{ID: m.ID, ProjectId: m.ProjectId, Role: m.Role, InvitedBy: m.InvitedBy,
+		Status: m.Status, CreatedAt: m.CreatedAt}
+	if m.Project != nil {
+		res.ProjectName = m.Project.Name
+	}
+	return res
+}
+
+// member 返回当前用户在项目中的成员记录，要求已接受邀请并具有 roles 中的某个角色（为空时不限制）。
+// 不是成员时按项目不存在处理，避免泄露项目是否存在
+func (h *ProjectHandler) member(c *gin.Context, uid, projectID uint, roles ...string) (*models.ProjectMember, bool) {
+	member, err := h.repo.WithContext(c.Request.Context()).GetMember(projectID, uid)
+	if err != nil || member.Status != models.MemberAccepted {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrProjectNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return nil, false
+	}
+	if len(roles) > 0 {
+		allowed := false
+		for _, role := range roles {
+			allowed = allowed || member.Role == role
+		}
+		if !allowed {
+			_ = c.Error(ierr.ErrForbidden)
+			return nil, false
+		}
+	}
+	return member, true
+}
+
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// CreateProject godoc
+// @Summary      创建共享项目
+// @Description  创建一个可以与其他用户共享的待办清单，创建者成为项目的所有者
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param        input  body      CreateProjectInput  true  "项目名称"
+// @Success      200    {object}  ProjectResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "未授权"
+// @Router       /projects [post]
+// @Security    BearerAuth
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input CreateProjectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	project := models.Project{Name: input.Name, UserId: uid.(uint)}
+	if err := h.repo.WithContext(c.Request.Context()).Create(&project); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, newProjectResponse(&project, models.RoleOwner))
+}
+
+// ListProjects godoc
+// @Summary      获取项目列表
+// @Description  列出当前用户已加入的项目及其角色
+// @Tags         projects
+// @Produce      json
+// @Success      200  {array}   ProjectResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Router       /projects [get]
+// @Security    BearerAuth
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	members, err := h.repo.WithContext(c.Request.Context()).ListByUser(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	res := make([]ProjectResponse, 0, len(members))
+	for _, m := range members {
+		if m.Project != nil {
+			res = append(res, newProjectResponse(m.Project, m.Role))
+		}
+	}
+	response.Success(c, res)
+}
+
+// GetProject godoc
+// @Summary      获取项目详情
+// @Description  返回项目信息、成员和待处理的邀请，只有项目成员可以查看
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {object}  ProjectDetailResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "项目不存在"
+// @Router       /projects/{id} [get]
+// @Security    BearerAuth
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	member, ok := h.member(c, uid.(uint), projectID)
+	if !ok {
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	project, err := repo.GetById(projectID)
+	if err != nil {
+		_ = c.Error(ierr.ErrProjectNotFound)
+		return
+	}
+	members, err := repo.ListMembers(projectID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	res := ProjectDetailResponse{ProjectResponse: newProjectResponse(project, member.Role),
+		Members: make([]MemberResponse, 0, len(members))}
+	for _, m := range members {
+		item := MemberResponse{ID: m.ID, UserId: m.UserId, Role: m.Role, Status: m.Status, CreatedAt: m.CreatedAt}
+		if m.User != nil {
+			item.Username = m.User.Username
+		}
+		res.Members = append(res.Members, item)
+	}
+	response.Success(c, res)
+}
+
+// InviteMember godoc
+// @Summary      邀请成员
+// @Description  按用户名邀请其他用户加入项目，对方接受后才能访问项目中的待办事项。只有所有者可以邀请
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                true  "项目ID"
+// @Param        input  body      InviteMemberInput  true  "用户名和角色"
+// @Success      200    {object}  InvitationResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      403    {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404    {object}  map[string]interface{}  "项目或用户不存在"
+// @Failure      409    {object}  map[string]interface{}  "已经是成员或已邀请"
+// @Router       /projects/{id}/members [post]
+// @Security    BearerAuth
+func (h *ProjectHandler) InviteMember(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var input InviteMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, models.RoleOwner); !ok {
+		return
+	}
+	user, err := h.users.WithContext(c.Request.Context()).GetUserByUsername(input.Username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrUserNotFound)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	project, err := repo.GetById(projectID)
+	if err != nil {
+		_ = c.Error(ierr.ErrProjectNotFound)
+		return
+	}
+	invitation := models.ProjectMember{ProjectId: projectID, Project: project, UserId: user.ID, Role: input.Role,
+		InvitedBy: uid.(uint)}
+	if err := repo.Invite(&invitation); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			_ = c.Error(ierr.ErrAlreadyMember)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, newInvitationResponse(&invitation))
+}
+
+// UpdateMember godoc
+// @Summary      修改成员角色
+// @Description  只有所有者可以修改，项目至少需要保留一个所有者
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                true  "项目ID"
+// @Param        uid    path      int                true  "成员的用户ID"
+// @Param        input  body      UpdateMemberInput  true  "新角色"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404    {object}  map[string]interface{}  "项目或成员不存在"
+// @Failure      409    {object}  map[string]interface{}  "不能降级最后一个所有者"
+// @Router       /projects/{id}/members/{uid} [put]
+// @Security    BearerAuth
+func (h *ProjectHandler) UpdateMember(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := parseID(c, "uid")
+	if !ok {
+		return
+	}
+	var input UpdateMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, models.RoleOwner); !ok {
+		return
+	}
+	if err := h.repo.WithContext(c.Request.Context()).UpdateRole(projectID, memberID, input.Role); err != nil {
+		h.memberError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RemoveMember godoc
+// @Summary      移除成员
+// @Description  所有者可以移除成员或撤回邀请，成员也可以移除自己以退出项目。项目至少需要保留一个所有者
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Param        uid  path      int  true  "成员的用户ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404  {object}  map[string]interface{}  "项目或成员不存在"
+// @Failure      409  {object}  map[string]interface{}  "不能移除最后一个所有者"
+// @Router       /projects/{id}/members/{uid} [delete]
+// @Security    BearerAuth
+func (h *ProjectHandler) RemoveMember(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := parseID(c, "uid")
+	if !ok {
+		return
+	}
+	var roles []string
+	if memberID != uid.(uint) {
+		roles = []string{models.RoleOwner}
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, roles...); !ok {
+		return
+	}
+	if err := h.repo.WithContext(c.Request.Context()).RemoveMember(projectID, memberID); err != nil {
+		h.memberError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *ProjectHandler) memberError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = c.Error(ierr.ErrMemberNotFound)
+	case errors.Is(err, repository.ErrLastOwner):
+		_ = c.Error(ierr.ErrLastOwner)
+	default:
+		_ = c.Error(ierr.ErrSystem)
+	}
+}
+
+// ListInvitations godoc
+// @Summary      获取待处理的邀请
+// @Description  列出其他用户发给当前用户、尚未接受或拒绝的项目邀请
+// @Tags         projects
+// @Produce      json
+// @Success      200  {array}   InvitationResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Router       /invitations [get]
+// @Security    BearerAuth
+func (h *ProjectHandler) ListInvitations(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	invitations, err := h.repo.WithContext(c.Request.Context()).ListInvitations(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	res := make([]InvitationResponse, len(invitations))
+	for i := range invitations {
+		res[i] = newInvitationResponse(&invitations[i])
+	}
+	response.Success(c, res)
+}
+
+// AcceptInvitation godoc
+// @Summary      接受邀请
+// @Description  接受后即成为项目成员，项目中的待办事项会出现在待办列表中
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "邀请ID"
+// @Success      200  {object}  InvitationResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "邀请不存在或已处理"
+// @Router       /invitations/{id}/accept [post]
+// @Security    BearerAuth
+func (h *ProjectHandler) AcceptInvitation(c *gin.Context) {
+	h.respond(c, true)
+}
+
+// DeclineInvitation godoc
+// @Summary      拒绝邀请
+// @Description  拒绝后所有者可以重新邀请
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "邀请ID"
+// @Success      200  {object}  InvitationResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "邀请不存在或已处理"
+// @Router       /invitations/{id}/decline [post]
+// @Security    BearerAuth
+func (h *ProjectHandler) DeclineInvitation(c *gin.Context) {
+	h.respond(c, false)
+}
+
+func (h *ProjectHandler) respond(c *gin.Context, accept bool) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	invitation, err := h.repo.WithContext(c.Request.Context()).RespondInvitation(uid.(uint), id, accept)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrInvitationNotFound)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, newInvitationResponse(invitation))
+}
@@ -1,32 +1,96 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+	"todolist-api/internal/metrics"
 	"todolist-api/internal/models"
 	"todolist-api/internal/repository"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/config"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TodoHandler struct {
-	repo repository.TodoRepository
+	repo     repository.TodoRepository
+	notifier *services.Notifier
+	audit    *services.AuditLog
+	undo     repository.UndoRepository
+	cfg      *config.TodosConfig
 }
 
-func NewTodoHandler(t repository.TodoRepository) *TodoHandler {
-	return &TodoHandler{repo: t}
+func NewTodoHandler(t repository.TodoRepository, notifier *services.Notifier, audit *services.AuditLog,
+	undo repository.UndoRepository, cfg *config.TodosConfig) *TodoHandler {
+	return &TodoHandler{repo: t, notifier: notifier, audit: audit, undo: undo, cfg: cfg}
+}
+
+// todoError 把仓库返回的错误转换为响应
+func todoError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+	case errors.Is(err, repository.ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee has no access to this todo"})
+	case errors.Is(err, repository.ErrInvalidMove), errors.Is(err, repository.ErrInvalidState),
+		errors.Is(err, repository.ErrInvalidBlocker):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrDependencyCycle), errors.Is(err, repository.ErrBlocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// issueUndoToken 为刚完成的操作生成撤销令牌，通过 X-Undo-Token 和 X-Undo-Expires-At 响应头返回。
+// before 是操作之前的待办事项。操作已经完成，保存令牌失败时只记录日志，不返回令牌
+func (h *TodoHandler) issueUndoToken(c *gin.Context, uid uint, action string, before ...models.Todo) {
+	if h.cfg.UndoWindowSeconds <= 0 {
+		return
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to generate undo token", slog.Any("error", err))
+		return
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(time.Duration(h.cfg.UndoWindowSeconds) * time.Second)
+	err := h.undo.WithContext(c.Request.Context()).Create(&models.UndoToken{UserId: uid,
+		TokenHash: hashUndoToken(token), Action: action, Snapshot: before, ExpiresAt: expiresAt})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to save undo token", slog.Any("error", err))
+		return
+	}
+	c.Header("X-Undo-Token", token)
+	c.Header("X-Undo-Expires-At", expiresAt.UTC().Format(time.RFC3339))
+}
+
+func hashUndoToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // CreateTodo godoc
 // @Summary      创建新的Todo项目
-// @Description  为当前认证用户创建一个新的Todo项目
+// @Description  为当前认证用户创建一个新的Todo项目，指定 project_id 时创建在共享项目中，需要项目的编辑者或所有者角色。
+// @Description  assignee_id 可以指定有权查看该Todo的用户，被指派的用户会收到通知
 // @Tags         todos
 // @Accept       json
 // @Produce      json
 // @Param        todo           body      CreateTodoInput  true  "Todo信息"
 // @Success      201  {object}  models.Todo
-// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误或被指派的用户无权查看"
 // @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "没有项目的编辑权限"
 // @Failure      500  {object}  map[string]interface{}  "服务器内部错误"
 // @Router       /todos [post]
 // @Security    BearerAuth
@@ -36,28 +100,34 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
-	var input struct {
-		Title string `json:"title" binding:"required"`
-	}
+	var input CreateTodoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	todo := models.Todo{Title: input.Title, Status: false, UserId: uid.(uint)}
-	if err := h.repo.Create(&todo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	todo := models.Todo{Title: input.Title, Status: false, UserId: uid.(uint), ProjectId: input.ProjectId,
+		AssigneeId: input.AssigneeId}
+	if err := h.repo.WithContext(c.Request.Context()).Create(uid.(uint), &todo); err != nil {
+		todoError(c, err)
 		return
 	}
+	metrics.TodosCreated.Inc()
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoCreate, models.AuditEntityTodo, todo.ID,
+		nil, &todo))
+	h.notifier.TodoAssigned(c.Request.Context(), &todo, uid.(uint))
 	c.JSON(http.StatusCreated, todo)
 }
 
 // GetAllTodos godoc
 // @Summary      获取用户的所有Todo项目
-// @Description  获取当前认证用户的所有Todo项目列表
+// @Description  获取当前认证用户的所有Todo项目列表，包括已加入的共享项目中的Todo
 // @Tags         todos
 // @Accept       json
 // @Produce      json
+// @Param        assignee       query     string  false  "按负责人过滤：me 表示指派给自己，unassigned 表示未指派，或者用户ID"
+// @Param        sort           query     string  false  "排序：created（默认，按创建时间倒序）或 manual（按手动排序的位置）"
 // @Success      200  {array}   models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的过滤条件"
 // @Failure      401  {object}  map[string]interface{}  "未授权"
 // @Failure      500  {object}  map[string]interface{}  "服务器内部错误"
 // @Router       /todos [get]
@@ -68,7 +138,12 @@ func (h *TodoHandler) GetAllTodos(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
-	var todos, err = h.repo.GetAll(uid.(uint))
+	filter, err := parseTodoFilter(c, uid.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	todos, err := h.repo.WithContext(c.Request.Context()).GetAll(uid.(uint), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -90,7 +165,7 @@ func (h *TodoHandler) GetAllTodos(c *gin.Context) {
 // @Router       /todos/{id} [get]
 // @Security    BearerAuth
 func (h *TodoHandler) GetTodoById(c *gin.Context) {
-	_, exists := c.Get("uid")
+	uid, exists := c.Get("uid")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
@@ -101,9 +176,9 @@ func (h *TodoHandler) GetTodoById(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
-	todo, err := h.repo.GetById(uint(uintId))
+	todo, err := h.repo.WithContext(c.Request.Context()).GetById(uid.(uint), uint(uintId))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		todoError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, todo)
@@ -111,19 +186,24 @@ func (h *TodoHandler) GetTodoById(c *gin.Context) {
 
 // UpdateTodo godoc
 // @Summary      更新Todo项目状态
-// @Description  更新指定ID的Todo项目的完成状态
+// @Description  更新指定ID的Todo项目的完成状态，还有未完成的阻塞者时不能完成。
+// @Description  X-Undo-Token 响应头返回撤销令牌，可以在有效期内调用 /undo/{token} 恢复
 // @Tags         todos
 // @Accept       json
 // @Produce      json
 // @Param        id             path      int     true  "Todo ID"
 // @Success      200  {object}  models.Todo
+// @Header       200  {string}  X-Undo-Token       "撤销令牌"
+// @Header       200  {string}  X-Undo-Expires-At  "撤销令牌的过期时间"
 // @Failure      400  {object}  map[string]interface{}  "无效的ID格式"
 // @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
 // @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Failure      409  {object}  map[string]interface{}  "还有未完成的阻塞者"
 // @Router       /todos/{id} [put]
 // @Security    BearerAuth
 func (h *TodoHandler) UpdateTodo(c *gin.Context) {
-	_, exists := c.Get("uid")
+	uid, exists := c.Get("uid")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
@@ -134,30 +214,45 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
-
-	if err := h.repo.Update(uint(uintId)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	repo := h.repo.WithContext(c.Request.Context())
+	before, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if err := repo.Update(uid.(uint), uint(uintId)); err != nil {
+		todoError(c, err)
 		return
 	}
-	todo, _ := h.repo.GetById(uint(uintId))
+	todo, _ := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if todo != nil && todo.Status {
+		metrics.TodosCompleted.Inc()
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoUpdate, models.AuditEntityTodo, before.ID,
+		before, todo))
+	h.issueUndoToken(c, uid.(uint), models.UndoStatus, *before)
 	c.JSON(http.StatusOK, todo)
 }
 
 // DeleteTodo godoc
 // @Summary      删除Todo项目
-// @Description  删除指定ID的Todo项目
+// @Description  删除指定ID的Todo项目，X-Undo-Token 响应头返回撤销令牌，可以在有效期内调用 /undo/{token} 恢复
 // @Tags         todos
 // @Accept       json
 // @Produce      json
 // @Param        id             path      int     true  "Todo ID"
 // @Success      204  "删除成功"
+// @Header       204  {string}  X-Undo-Token       "撤销令牌"
+// @Header       204  {string}  X-Undo-Expires-At  "撤销令牌的过期时间"
 // @Failure      400  {object}  map[string]interface{}  "无效的ID格式"
 // @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
 // @Failure      500  {object}  map[string]interface{}  "删除失败"
 // @Router       /todos/{id} [delete]
 // @Security    BearerAuth
 func (h *TodoHandler) DeleteTodo(c *gin.Context) {
-	_, exists := c.Get("uid")
+	uid, exists := c.Get("uid")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
@@ -168,14 +263,515 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
-	if err := h.repo.Delete(uint(uintId)); err != nil {
+	repo := h.repo.WithContext(c.Request.Context())
+	before, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if err := repo.Delete(uid.(uint), uint(uintId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrForbidden) {
+			todoError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
 		return
 	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoDelete, models.AuditEntityTodo, before.ID,
+		before, nil))
+	h.issueUndoToken(c, uid.(uint), models.UndoDelete, *before)
 	c.Status(http.StatusNoContent)
 }
 
+// AssignTodo godoc
+// @Summary      指派Todo项目
+// @Description  把Todo指派给有权查看它的用户，assignee_id 为 null 时取消指派。需要修改权限，被指派的用户会收到通知
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int              true  "Todo ID"
+// @Param        assignee       body      AssignTodoInput  true  "负责人"
+// @Success      200  {object}  models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式或被指派的用户无权查看"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/assignee [put]
+// @Security    BearerAuth
+func (h *TodoHandler) AssignTodo(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var input AssignTodoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	before, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if err := repo.Assign(uid.(uint), uint(uintId), input.AssigneeId); err != nil {
+		todoError(c, err)
+		return
+	}
+	todo, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoAssign, models.AuditEntityTodo, todo.ID,
+		before, todo))
+	// 负责人没有变化时不再通知
+	if todo.AssigneeId != nil && (before.AssigneeId == nil || *before.AssigneeId != *todo.AssigneeId) {
+		h.notifier.TodoAssigned(c.Request.Context(), todo, uid.(uint))
+	}
+	c.JSON(http.StatusOK, todo)
+}
+
+// MoveTodo godoc
+// @Summary      手动排序Todo项目
+// @Description  把Todo移动到同一列表（同一个项目或自己的个人待办事项）中的 after 之后、before 之前，至少给出一个。
+// @Description  通常只修改被移动的Todo，使用 sort=manual 查询列表时按该顺序排列
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int            true  "Todo ID"
+// @Param        move           body      MoveTodoInput  true  "相邻的Todo"
+// @Success      200  {object}  models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式，或相邻的Todo不在同一列表中"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/move [post]
+// @Security    BearerAuth
+func (h *TodoHandler) MoveTodo(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var input MoveTodoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	before, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if err := repo.Move(uid.(uint), uint(uintId), input.Before, input.After); err != nil {
+		todoError(c, err)
+		return
+	}
+	todo, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoMove, models.AuditEntityTodo, todo.ID,
+		before, todo))
+	c.JSON(http.StatusOK, todo)
+}
+
+// SetTodoState godoc
+// @Summary      移动Todo到工作流状态
+// @Description  把项目中的Todo移动到该项目的工作流状态，status 由状态是否算作完成决定。
+// @Description  X-Undo-Token 响应头返回撤销令牌，可以在有效期内调用 /undo/{token} 恢复
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int                true  "Todo ID"
+// @Param        state          body      SetTodoStateInput  true  "工作流状态"
+// @Success      200  {object}  models.Todo
+// @Header       200  {string}  X-Undo-Token       "撤销令牌"
+// @Header       200  {string}  X-Undo-Expires-At  "撤销令牌的过期时间"
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式，或状态不属于Todo所在的项目"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Failure      409  {object}  map[string]interface{}  "移动到算作完成的状态，但还有未完成的阻塞者"
+// @Router       /todos/{id}/state [put]
+// @Security    BearerAuth
+func (h *TodoHandler) SetTodoState(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var input SetTodoStateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	before, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if err := repo.SetState(uid.(uint), uint(uintId), input.StateId); err != nil {
+		todoError(c, err)
+		return
+	}
+	todo, err := repo.Primary().GetById(uid.(uint), uint(uintId))
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	if todo.Status && !before.Status {
+		metrics.TodosCompleted.Inc()
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditTodoState, models.AuditEntityTodo, todo.ID,
+		before, todo))
+	h.issueUndoToken(c, uid.(uint), models.UndoStatus, *before)
+	c.JSON(http.StatusOK, todo)
+}
+
+// TodoStateHistory godoc
+// @Summary      获取Todo在工作流状态中停留的时间
+// @Description  按时间顺序返回Todo进入各个工作流状态的记录，以及在每个状态中累计停留的秒数，当前所处的状态计算到现在
+// @Tags         todos
+// @Produce      json
+// @Param        id             path      int     true  "Todo ID"
+// @Success      200  {object}  StateHistoryResponse
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/state-history [get]
+// @Security    BearerAuth
+func (h *TodoHandler) TodoStateHistory(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	if _, err := repo.GetById(uid.(uint), uint(uintId)); err != nil {
+		todoError(c, err)
+		return
+	}
+	transitions, err := repo.StateTransitions(uint(uintId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, newStateHistoryResponse(transitions, time.Now()))
+}
+
+// StateDuration 在一个工作流状态中累计停留的时间
+type StateDuration struct {
+	StateId uint   `json:"state_id" example:"2"`
+	Name    string `json:"name" example:"In Progress"`
+	Seconds int64  `json:"seconds" example:"3600"`
+}
+
+// StateHistoryResponse Todo进入工作流状态的记录和在每个状态中停留的时间
+type StateHistoryResponse struct {
+	Transitions []models.TodoStateTransition `json:"transitions"`
+	// Durations 按第一次进入的顺序排列
+	Durations []StateDuration `json:"durations"`
+}
+
+// newStateHistoryResponse 累计每个状态的停留时间，还没有离开的状态计算到 now
+func newStateHistoryResponse(transitions []models.TodoStateTransition, now time.Time) StateHistoryResponse {
+	res := StateHistoryResponse{Transitions: transitions, Durations: []StateDuration{}}
+	if res.Transitions == nil {
+		res.Transitions = []models.TodoStateTransition{}
+	}
+	index := map[uint]int{}
+	totals := []time.Duration{}
+	for _, t := range transitions {
+		i, ok := index[t.StateId]
+		if !ok {
+			i = len(res.Durations)
+			index[t.StateId] = i
+			duration := StateDuration{StateId: t.StateId}
+			if t.State != nil {
+				duration.Name = t.State.Name
+			}
+			res.Durations = append(res.Durations, duration)
+			totals = append(totals, 0)
+		}
+		end := now
+		if t.LeftAt != nil {
+			end = *t.LeftAt
+		}
+		totals[i] += end.Sub(t.EnteredAt)
+	}
+	for i := range res.Durations {
+		res.Durations[i].Seconds = int64(totals[i] / time.Second)
+	}
+	return res
+}
+
+// ListBlockers godoc
+// @Summary      获取阻塞Todo的待办事项
+// @Description  返回指定Todo要等待完成的待办事项，按手动排序的顺序排列
+// @Tags         todos
+// @Produce      json
+// @Param        id             path      int     true  "Todo ID"
+// @Success      200  {array}   models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Router       /todos/{id}/blockers [get]
+// @Security    BearerAuth
+func (h *TodoHandler) ListBlockers(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	if _, err := repo.GetById(uid.(uint), uint(uintId)); err != nil {
+		todoError(c, err)
+		return
+	}
+	todos, err := repo.Blockers(uint(uintId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, todos)
+}
+
+// AddBlocker godoc
+// @Summary      添加阻塞关系
+// @Description  指定的Todo要等 blocker_id 完成之后才能完成。两者必须在同一列表（同一个项目或自己的个人待办事项）中，
+// @Description  并且不能形成环。需要修改权限
+// @Tags         todos
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int              true  "Todo ID"
+// @Param        blocker        body      AddBlockerInput  true  "阻塞者"
+// @Success      200  {object}  models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式，或阻塞者不在同一列表中"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo未找到"
+// @Failure      409  {object}  map[string]interface{}  "会形成环"
+// @Router       /todos/{id}/blockers [post]
+// @Security    BearerAuth
+func (h *TodoHandler) AddBlocker(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var input AddBlockerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.changeBlocker(c, uid.(uint), uint(uintId), input.BlockerId, models.AuditTodoBlock)
+}
+
+// RemoveBlocker godoc
+// @Summary      删除阻塞关系
+// @Description  指定的Todo不再等待 bid 完成，需要修改权限
+// @Tags         todos
+// @Produce      json
+// @Param        id             path      int     true  "Todo ID"
+// @Param        bid            path      int     true  "阻塞者的Todo ID"
+// @Success      200  {object}  models.Todo
+// @Failure      400  {object}  map[string]interface{}  "无效的ID格式"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      403  {object}  map[string]interface{}  "只有查看权限"
+// @Failure      404  {object}  map[string]interface{}  "Todo或阻塞关系未找到"
+// @Router       /todos/{id}/blockers/{bid} [delete]
+// @Security    BearerAuth
+func (h *TodoHandler) RemoveBlocker(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	uintId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	blockerID, err := strconv.ParseUint(c.Param("bid"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	h.changeBlocker(c, uid.(uint), uint(uintId), uint(blockerID), models.AuditTodoUnblock)
+}
+
+// changeBlocker 添加或删除阻塞关系，记录审计事件并返回修改后的Todo
+func (h *TodoHandler) changeBlocker(c *gin.Context, uid, id, blockerID uint, action string) {
+	repo := h.repo.WithContext(c.Request.Context())
+	var before, after any
+	if action == models.AuditTodoBlock {
+		after = gin.H{"blocker_id": blockerID}
+		if err := repo.AddBlocker(uid, id, blockerID); err != nil {
+			todoError(c, err)
+			return
+		}
+	} else {
+		before = gin.H{"blocker_id": blockerID}
+		if err := repo.RemoveBlocker(uid, id, blockerID); err != nil {
+			todoError(c, err)
+			return
+		}
+	}
+	todo, err := repo.Primary().GetById(uid, id)
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, action, models.AuditEntityTodo, id, before, after))
+	c.JSON(http.StatusOK, todo)
+}
+
+// DependencyGraph godoc
+// @Summary      获取Todo的依赖图
+// @Description  返回一个列表中的Todo和它们之间的阻塞关系，Todo按拓扑顺序排列：阻塞者排在被阻塞的Todo之前，
+// @Description  没有先后要求的按手动排序的顺序。指定 project_id 时返回该项目，否则返回自己的个人待办事项
+// @Tags         todos
+// @Produce      json
+// @Param        project_id     query     int     false  "项目ID"
+// @Success      200  {object}  DependencyGraphResponse
+// @Failure      400  {object}  map[string]interface{}  "无效的项目ID"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "项目不存在或不是成员"
+// @Router       /todos/graph [get]
+// @Security    BearerAuth
+func (h *TodoHandler) DependencyGraph(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	var projectID *uint
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+			return
+		}
+		pid := uint(id)
+		projectID = &pid
+	}
+	todos, deps, err := h.repo.WithContext(c.Request.Context()).DependencyGraph(uid.(uint), projectID)
+	if err != nil {
+		todoError(c, err)
+		return
+	}
+	res := DependencyGraphResponse{Todos: todos, Dependencies: deps}
+	if res.Todos == nil {
+		res.Todos = []models.Todo{}
+	}
+	if res.Dependencies == nil {
+		res.Dependencies = []models.TodoDependency{}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// DependencyGraphResponse 一个列表的依赖图
+type DependencyGraphResponse struct {
+	// Todos 按拓扑顺序排列，阻塞者在前
+	Todos []models.Todo `json:"todos"`
+	// Dependencies Todo之间的阻塞关系
+	Dependencies []models.TodoDependency `json:"dependencies"`
+}
+
+// parseTodoFilter 解析列表的查询参数
+func parseTodoFilter(c *gin.Context, uid uint) (repository.TodoFilter, error) {
+	var filter repository.TodoFilter
+	switch sort := c.Query("sort"); sort {
+	case "", "created":
+	case repository.SortManual:
+		filter.Sort = repository.SortManual
+	default:
+		return filter, errors.New("sort must be created or manual")
+	}
+	switch assignee := c.Query("assignee"); assignee {
+	case "":
+	case "me":
+		filter.AssigneeId = &uid
+	case "unassigned":
+		filter.Unassigned = true
+	default:
+		id, err := strconv.ParseUint(assignee, 10, 32)
+		if err != nil {
+			return filter, errors.New("assignee must be me, unassigned or a user ID")
+		}
+		assigneeID := uint(id)
+		filter.AssigneeId = &assigneeID
+	}
+	return filter, nil
+}
+
 // CreateTodoInput 定义了创建Todo时的输入结构
 type CreateTodoInput struct {
 	Title string `json:"title" binding:"required" example:"完成项目文档"`
+	// ProjectId 创建在哪个共享项目中，为空表示个人待办事项
+	ProjectId *uint `json:"project_id" example:"1"`
+	// AssigneeId 负责人，为空表示未指派
+	AssigneeId *uint `json:"assignee_id" example:"2"`
+}
+
+// MoveTodoInput 手动排序时的输入结构
+type MoveTodoInput struct {
+	// Before 移动到这个Todo之前
+	Before *uint `json:"before" example:"3"`
+	// After 移动到这个Todo之后
+	After *uint `json:"after" example:"2"`
+}
+
+// SetTodoStateInput 移动Todo到工作流状态时的输入结构
+type SetTodoStateInput struct {
+	// StateId Todo所在项目的工作流状态
+	StateId uint `json:"state_id" binding:"required" example:"2"`
+}
+
+// AddBlockerInput 添加阻塞关系时的输入结构
+type AddBlockerInput struct {
+	// BlockerId 同一列表中要先完成的Todo
+	BlockerId uint `json:"blocker_id" binding:"required" example:"1"`
+}
+
+// AssignTodoInput 指派Todo时的输入结构
+type AssignTodoInput struct {
+	// AssigneeId 负责人，为 null 时取消指派
+	AssigneeId *uint `json:"assignee_id" example:"2"`
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStateHistoryResponse(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	backlog := &models.WorkflowState{ID: 1, Name: "Backlog"}
+	review := &models.WorkflowState{ID: 2, Name: "Review"}
+	transitions := []models.TodoStateTransition{
+		{StateId: 1, State: backlog, EnteredAt: start, LeftAt: at(10)},
+		{StateId: 2, State: review, EnteredAt: *at(10), LeftAt: at(40)},
+		// 再次进入 Backlog，时间累加到第一次进入的位置
+		{StateId: 1, State: backlog, EnteredAt: *at(40), LeftAt: at(45)},
+		// 还没有离开的状态计算到 now
+		{StateId: 2, State: review, EnteredAt: *at(45)},
+	}
+
+	res := newStateHistoryResponse(transitions, *at(60))
+	assert.Equal(t, transitions, res.Transitions)
+	assert.Equal(t, []StateDuration{
+		{StateId: 1, Name: "Backlog", Seconds: 15 * 60},
+		{StateId: 2, Name: "Review", Seconds: 45 * 60},
+	}, res.Durations)
+
+	empty := newStateHistoryResponse(nil, start)
+	assert.NotNil(t, empty.Transitions)
+	assert.Empty(t, empty.Durations)
+}
+
+func TestParseTodoFilter(t *testing.T) {
+	parse := func(query string) (repository.TodoFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/todos?"+query, nil)
+		return parseTodoFilter(c, 7)
+	}
+	filter, err := parse("assignee=me")
+	require.NoError(t, err)
+	require.NotNil(t, filter.AssigneeId)
+	assert.EqualValues(t, 7, *filter.AssigneeId)
+
+	filter, err = parse("assignee=unassigned&sort=manual")
+	require.NoError(t, err)
+	assert.Equal(t, repository.TodoFilter{Unassigned: true, Sort: repository.SortManual}, filter)
+
+	filter, err = parse("assignee=3")
+	require.NoError(t, err)
+	require.NotNil(t, filter.AssigneeId)
+	assert.EqualValues(t, 3, *filter.AssigneeId)
+
+	_, err = parse("assignee=someone")
+	assert.Error(t, err)
+	_, err = parse("sort=title")
+	assert.Error(t, err)
+}
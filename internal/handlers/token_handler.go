@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	repo    repository.PersonalTokenRepository
+	service *services.PersonalTokenService
+	audit   *services.AuditLog
+}
+
+func NewTokenHandler(repo repository.PersonalTokenRepository, service *services.PersonalTokenService,
+	audit *services.AuditLog) *TokenHandler {
+	return &TokenHandler{repo: repo, service: service, audit: audit}
+}
+
+// CreateTokenInput 创建个人访问令牌的参数
+type CreateTokenInput struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"ci-script"`
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"todos:read,todos:write"`
+	// ExpiresInDays 有效天数，为空或 0 表示永不过期
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0,max=3650" example:"90"`
+}
+
+// TokenResponse 个人访问令牌的信息，不包含令牌明文
+type TokenResponse struct {
+	ID         uint       `json:"id" example:"1"`
+	Name       string     `json:"name" example:"ci-script"`
+	Prefix     string     `json:"prefix" example:"tdl_3f2a9c"`
+	Scopes     []string   `json:"scopes" example:"todos:read"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" example:"127.0.0.1"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateTokenResponse 创建成功后返回令牌明文，只会展示这一次
+type CreateTokenResponse struct {
+	TokenResponse
+	Token string `json:"token" example:"tdl_3f2a9c..."`
+}
+
+func newTokenResponse(t *models.PersonalAccessToken) TokenResponse {
+	return TokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.ScopeList(),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		LastUsedIP: t.LastUsedIP,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// CreateToken godoc
+// @Summary      创建个人访问令牌
+// @Description  为脚本和第三方集成创建具有指定权限范围的令牌，令牌明文只会在本次响应中返回
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        input  body      CreateTokenInput  true  "令牌名称、权限范围和有效期"
+// @Success      200    {object}  CreateTokenResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "未授权"
+// @Router       /user/tokens [post]
+// @Security    BearerAuth
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input CreateTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	var expiresAt *time.Time
+	if input.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		expiresAt = &t
+	}
+	plain, token, err := h.service.Create(uid.(uint), input.Name, input.Scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownScope) {
+			_ = c.Error(ierr.ErrInvalidScope)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	// 只记录令牌的信息，不包含明文
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserTokenCreate, models.AuditEntityUser,
+		uid.(uint), nil, gin.H{"token_id": token.ID, "name": token.Name, "prefix": token.Prefix,
+			"scopes": token.ScopeList(), "expires_at": token.ExpiresAt}))
+	response.Success(c, CreateTokenResponse{TokenResponse: newTokenResponse(token), Token: plain})
+}
+
+// ListTokens godoc
+// @Summary      获取个人访问令牌列表
+// @Description  列出当前用户未撤销的个人访问令牌，包括最近使用的时间和IP
+// @Tags         tokens
+// @Produce      json
+// @Success      200  {array}   TokenResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Router       /user/tokens [get]
+// @Security    BearerAuth
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	tokens, err := h.repo.ListByUser(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	res := make([]TokenResponse, len(tokens))
+	for i := range tokens {
+		res[i] = newTokenResponse(&tokens[i])
+	}
+	response.Success(c, res)
+}
+
+// RevokeToken godoc
+// @Summary      撤销个人访问令牌
+// @Description  撤销后该令牌立即失效
+// @Tags         tokens
+// @Produce      json
+// @Param        id   path      int  true  "令牌ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "令牌不存在"
+// @Router       /user/tokens/{id} [delete]
+// @Security    BearerAuth
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if err := h.repo.Revoke(uid.(uint), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrTokenNotFound)
+			return
+		}
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserTokenRevoke, models.AuditEntityUser,
+		uid.(uint), gin.H{"token_id": id}, nil))
+	response.Success(c, nil)
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+	"todolist-api/pkg/totp"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// totpIssuer 显示在认证器 App 中的发行方名称
+	totpIssuer = "todolist"
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+// TOTPEnrollResponse 开始绑定两步验证时返回的密钥
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URI    string `json:"otpauth_uri" example:"otpauth://totp/todolist:johndoe?secret=JBSWY3DPEHPK3PXP&issuer=todolist"`
+}
+
+// TOTPCodeInput 提交的 TOTP 验证码
+type TOTPCodeInput struct {
+	Code string `json:"code" binding:"required,len=6" example:"123456"`
+}
+
+// LoginTwoFactorInput 用挑战令牌和验证码（或恢复码）换取正式令牌
+type LoginTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" example:"123456"`
+	RecoveryCode   string `json:"recovery_code" example:"abcd-efgh"`
+}
+
+// PasswordConfirmInput 敏感操作前的密码确认
+type PasswordConfirmInput struct {
+	Password string `json:"password" binding:"required" example:"password123"`
+}
+
+// TwoFactorChallengeResponse 启用了两步验证的用户登录时返回的挑战
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required" example:"true"`
+	ChallengeToken    string `json:"challenge_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// RecoveryCodesResponse 新生成的恢复码，只会展示这一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTOTP godoc
+// @Summary      开始绑定两步验证
+// @Description  生成新的 TOTP 密钥并返回 otpauth:// 地址，需调用确认接口后才会生效
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  TOTPEnrollResponse
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      409  {object}  map[string]interface{}  "已启用两步验证"
+// @Router       /user/2fa/enroll [post]
+// @Security    BearerAuth
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	user, err := h.repo.GetUserById(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrUserNotFound)
+		return
+	}
+	if user.TOTPEnabled {
+		_ = c.Error(ierr.ErrTOTPAlreadyEnabled)
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	if err := h.repo.UpdateUser(user, map[string]any{"totp_secret": secret, "totp_last_step": 0}); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserTOTPEnroll, models.AuditEntityUser,
+		user.ID, nil, nil))
+	response.Success(c, TOTPEnrollResponse{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary      确认绑定两步验证
+// @Description  使用认证器 App 生成的验证码确认绑定，成功后启用两步验证并返回一次性恢复码
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      TOTPCodeInput  true  "验证码"
+// @Success      200    {object}  RecoveryCodesResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "验证码错误"
+// @Router       /user/2fa/confirm [post]
+// @Security    BearerAuth
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	var input TOTPCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	user, err := h.repo.GetUserById(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrUserNotFound)
+		return
+	}
+	if user.TOTPEnabled {
+		_ = c.Error(ierr.ErrTOTPAlreadyEnabled)
+		return
+	}
+	if user.TOTPSecret == "" {
+		_ = c.Error(ierr.ErrTOTPNotEnrolled)
+		return
+	}
+	step, ok := totp.Validate(user.TOTPSecret, input.Code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		_ = c.Error(ierr.ErrInvalidTOTPCode)
+		return
+	}
+	codes, err := h.issueRecoveryCodes(user.ID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	before := *user
+	if err := h.repo.UpdateUser(user, map[string]any{"totp_enabled": true, "totp_last_step": step}); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserTOTPEnable, models.AuditEntityUser,
+		user.ID, &before, user))
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// LoginTwoFactor godoc
+// @Summary      两步验证登录
+// @Description  使用登录接口返回的挑战令牌和 TOTP 验证码（或恢复码）换取JWT令牌
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      LoginTwoFactorInput  true  "挑战令牌和验证码"
+// @Success      200    {object}  LoginResponse
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401    {object}  map[string]interface{}  "挑战令牌或验证码无效"
+// @Router       /user/login/2fa [post]
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var input LoginTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Code == "") == (input.RecoveryCode == "") {
+		// 验证码和恢复码必须且只能提供一个
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	claims, err := h.authService.VerifyChallengeToken(input.ChallengeToken)
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidChallenge)
+		return
+	}
+	user, err := h.repo.GetUserById(claims.UserId)
+	if err != nil {
+		_ = c.Error(ierr.ErrInvalidChallenge)
+		return
+	}
+	if !user.TOTPEnabled {
+		_ = c.Error(ierr.ErrTOTPNotEnabled)
+		return
+	}
+	if input.Code != "" {
+		step, ok := totp.Validate(user.TOTPSecret, input.Code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			_ = c.Error(ierr.ErrInvalidTOTPCode)
+			return
+		}
+		if advanced, err := h.repo.AdvanceTOTPStep(user.ID, step); err != nil || !advanced {
+			_ = c.Error(ierr.ErrInvalidTOTPCode)
+			return
+		}
+	} else if err := h.recoveryRepo.Use(user.ID, hashRecoveryCode(input.RecoveryCode)); err != nil {
+		_ = c.Error(ierr.ErrInvalidTOTPCode)
+		return
+	}
+	token, err := h.authService.GenerateToken(user)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, token)
+}
+
+// DisableTOTP godoc
+// @Summary      关闭两步验证
+// @Description  验证密码后关闭两步验证，并作废所有恢复码
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      PasswordConfirmInput  true  "当前密码"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      401    {object}  map[string]interface{}  "密码错误"
+// @Router       /user/2fa/disable [post]
+// @Security    BearerAuth
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	user, ok := h.reauthenticate(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		_ = c.Error(ierr.ErrTOTPNotEnabled)
+		return
+	}
+	before := *user
+	if err := h.repo.UpdateUser(user, map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	if err := h.recoveryRepo.DeleteAll(user.ID); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserTOTPDisable, models.AuditEntityUser,
+		user.ID, &before, user))
+	response.Success(c, nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      重新生成恢复码
+// @Description  验证密码后生成一组新的恢复码，旧的恢复码全部失效
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      PasswordConfirmInput  true  "当前密码"
+// @Success      200    {object}  RecoveryCodesResponse
+// @Failure      401    {object}  map[string]interface{}  "密码错误"
+// @Router       /user/2fa/recovery-codes [post]
+// @Security    BearerAuth
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.reauthenticate(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		_ = c.Error(ierr.ErrTOTPNotEnabled)
+		return
+	}
+	codes, err := h.issueRecoveryCodes(user.ID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	h.audit.Record(c.Request.Context(), newAuditEvent(c, models.AuditUserRecoveryCodesGenerate,
+		models.AuditEntityUser, user.ID, nil, nil))
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// reauthenticate 校验当前登录用户提交的密码，失败时已写入错误
+func (h *UserHandler) reauthenticate(c *gin.Context) (*models.User, bool) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return nil, false
+	}
+	var input PasswordConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return nil, false
+	}
+	user, err := h.repo.GetUserById(uid.(uint))
+	if err != nil {
+		_ = c.Error(ierr.ErrUserNotFound)
+		return nil, false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		_ = c.Error(ierr.ErrInvalidCredentials)
+		return nil, false
+	}
+	return user, true
+}
+
+// issueRecoveryCodes 生成一组新的恢复码，保存哈希并返回明文
+func (h *UserHandler) issueRecoveryCodes(uid uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, 5)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := h.recoveryRepo.Replace(uid, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 忽略大小写、空格和连字符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UndoHandler struct {
+	undo  repository.UndoRepository
+	todos repository.TodoRepository
+	audit *services.AuditLog
+}
+
+func NewUndoHandler(undo repository.UndoRepository, todos repository.TodoRepository,
+	audit *services.AuditLog) *UndoHandler {
+	return &UndoHandler{undo: undo, todos: todos, audit: audit}
+}
+
+// Undo godoc
+// @Summary      撤销操作
+// @Description  使用删除、修改完成状态等操作返回的 X-Undo-Token 撤销该操作，恢复被删除的Todo和之前的完成状态。
+// @Description  令牌只能由执行操作的用户在有效期内使用一次，撤销在一个事务中完成，返回恢复后的Todo
+// @Tags         todos
+// @Produce      json
+// @Param        token  path      string  true  "撤销令牌"
+// @Success      200    {array}   models.Todo
+// @Failure      401    {object}  map[string]interface{}  "未授权"
+// @Failure      403    {object}  map[string]interface{}  "已经没有修改权限"
+// @Failure      404    {object}  map[string]interface{}  "令牌无效或已过期，或Todo已被彻底清除"
+// @Failure      409    {object}  map[string]interface{}  "要恢复为已完成的Todo还有未完成的阻塞者"
+// @Router       /undo/{token} [post]
+// @Security    BearerAuth
+func (h *UndoHandler) Undo(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	ctx := c.Request.Context()
+	before, err := h.undo.WithContext(ctx).Apply(uid.(uint), hashUndoToken(c.Param("token")))
+	switch {
+	case err == nil:
+	case errors.Is(err, repository.ErrInvalidUndoToken):
+		_ = c.Error(ierr.ErrInvalidUndoToken)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = c.Error(ierr.ErrTodoNotFound)
+		return
+	case errors.Is(err, repository.ErrForbidden):
+		_ = c.Error(ierr.ErrForbidden)
+		return
+	case errors.Is(err, repository.ErrBlocked):
+		_ = c.Error(ierr.ErrTodoBlocked)
+		return
+	default:
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	todos := h.todos.WithContext(ctx).Primary()
+	restored := make([]models.Todo, 0, len(before))
+	for i := range before {
+		// 撤销完成状态时待办事项可能已经被删除，仍然保持删除
+		todo, err := todos.GetIncludingDeleted(uid.(uint), before[i].ID)
+		if err != nil {
+			_ = c.Error(ierr.ErrSystem)
+			return
+		}
+		h.audit.Record(ctx, newAuditEvent(c, models.AuditTodoUndo, models.AuditEntityTodo, todo.ID, &before[i], todo))
+		restored = append(restored, *todo)
+	}
+	response.Success(c, restored)
+}
@@ -6,7 +6,10 @@ import (
 	"todolist-api/internal/models"
 	"todolist-api/internal/repository"
 	"todolist-api/internal/services"
+	"todolist-api/pkg/config"
 	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/mailer"
+	"todolist-api/pkg/password"
 	"todolist-api/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -15,14 +18,20 @@ import (
 )
 
 type UserHandler struct {
-	repo        repository.TodoRepository
-	authService *services.AuthService
+	repo         repository.TodoRepository
+	recoveryRepo repository.RecoveryCodeRepository
+	resetRepo    repository.PasswordResetRepository
+	authService  *services.AuthService
+	mailer       mailer.Mailer
+	passwordCfg  *config.PasswordConfig
+	audit        *services.AuditLog
 }
 
-// RegisterInput 定义了用户注册时需要绑定的数据
+// RegisterInput 定义了用户注册时需要绑定的数据，密码强度由密码策略校验
 type RegisterInput struct {
 	Username string `json:"username" binding:"required,min=4,max=20" example:"johndoe"`
-	Password string `json:"password" binding:"required,min=6,max=20" example:"password123"`
+	Password string `json:"password" binding:"required" example:"password123"`
+	Email    string `json:"email" binding:"omitempty,email" example:"john@example.com"`
 }
 
 // LoginInput 定义了用户登录时需要绑定的数据
@@ -41,8 +50,18 @@ type LoginResponse struct {
 	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
 }
 
-func NewUserHandler(repo repository.TodoRepository, authService *services.AuthService) *UserHandler {
-	return &UserHandler{repo: repo, authService: authService}
+func NewUserHandler(repo repository.TodoRepository, recoveryRepo repository.RecoveryCodeRepository,
+	resetRepo repository.PasswordResetRepository, authService *services.AuthService,
+	mailer mailer.Mailer, passwordCfg *config.PasswordConfig, audit *services.AuditLog) *UserHandler {
+	return &UserHandler{
+		repo:         repo,
+		recoveryRepo: recoveryRepo,
+		resetRepo:    resetRepo,
+		authService:  authService,
+		mailer:       mailer,
+		passwordCfg:  passwordCfg,
+		audit:        audit,
+	}
 }
 
 // Register godoc
@@ -64,13 +83,24 @@ func (h *UserHandler) Register(c *gin.Context) {
 		_ = c.Error(ierr.ErrInvalidInput)
 		return
 	}
+	if err := password.Validate(h.passwordCfg, input.Password, input.Username); err != nil {
+		_ = c.Error(ierr.ErrWeakPassword.WithMsg(err.Error()))
+		return
+	}
 
 	user := models.User{
 		Username: input.Username,
 		Password: input.Password,
 	}
+	if input.Email != "" {
+		if _, err := h.repo.WithContext(c.Request.Context()).GetUserByEmail(input.Email); err == nil {
+			_ = c.Error(ierr.ErrEmailExists)
+			return
+		}
+		user.Email = &input.Email
+	}
 
-	if err := h.repo.CreateUser(&user); err != nil {
+	if err := h.repo.WithContext(c.Request.Context()).CreateUser(&user); err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			// 抛出特定的业务错误
 			_ = c.Error(ierr.ErrUsernameExists)
@@ -82,6 +112,11 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// 注册时还没有登录，操作者就是新用户自己
+	event := newAuditEvent(c, models.AuditUserRegister, models.AuditEntityUser, user.ID, nil, &user)
+	event.ActorId = &user.ID
+	h.audit.Record(c.Request.Context(), event)
+
 	// 成功的响应保持不变
 	response.Success(c, gin.H{
 		"id":         user.ID,
@@ -92,7 +127,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary      用户登录
-// @Description  用户登录并获取JWT令牌
+// @Description  用户登录并获取JWT令牌；启用了两步验证的用户会得到挑战令牌，需调用 /user/login/2fa 换取JWT令牌
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -109,7 +144,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	user, err := h.repo.GetUserByUsername(input.Username)
+	user, err := h.repo.WithContext(c.Request.Context()).GetUserByUsername(input.Username)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
@@ -118,7 +153,16 @@ func (h *UserHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
-	token, err := h.authService.GenerateToken(user.ID)
+	if user.TOTPEnabled {
+		challenge, err := h.authService.GenerateChallengeToken(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.Success(c, TwoFactorChallengeResponse{TwoFactorRequired: true, ChallengeToken: challenge})
+		return
+	}
+	token, err := h.authService.GenerateToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"todolist-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WellKnownHandler struct {
+	authService *services.AuthService
+}
+
+func NewWellKnownHandler(authService *services.AuthService) *WellKnownHandler {
+	return &WellKnownHandler{authService: authService}
+}
+
+// JWKS godoc
+// @Summary      JWT 公钥集合
+// @Description  返回用于校验本服务签发的令牌的公钥（JWKS），包括轮换后仍在有效期内的旧密钥
+// @Tags         well-known
+// @Produce      json
+// @Success      200  {object}  services.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	// 允许其他服务缓存一段时间，轮换密钥时应先发布新公钥再切换签发密钥
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/pkg/ierr"
+	"todolist-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateStateInput 创建工作流状态的参数
+type CreateStateInput struct {
+	Name string `json:"name" binding:"required,max=50" example:"In Progress"`
+	// Done 处于该状态的待办事项是否算作已完成
+	Done bool `json:"done" example:"false"`
+}
+
+// UpdateStateInput 修改工作流状态的参数，只修改给出的字段
+type UpdateStateInput struct {
+	Name *string `json:"name" binding:"omitempty,min=1,max=50" example:"Review"`
+	Done *bool   `json:"done" example:"false"`
+	// Position 在项目中的顺序，从小到大排列
+	Position *int `json:"position" binding:"omitempty,min=0" example:"2"`
+}
+
+// ListStates godoc
+// @Summary      获取项目的工作流状态
+// @Description  按顺序返回项目自定义的工作流状态（看板的列），项目的所有成员都可以查看
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {array}   models.WorkflowState
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      404  {object}  map[string]interface{}  "项目不存在"
+// @Router       /projects/{id}/states [get]
+// @Security    BearerAuth
+func (h *ProjectHandler) ListStates(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID); !ok {
+		return
+	}
+	states, err := h.states.WithContext(c.Request.Context()).ListStates(projectID)
+	if err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	if states == nil {
+		states = []models.WorkflowState{}
+	}
+	response.Success(c, states)
+}
+
+// CreateState godoc
+// @Summary      创建工作流状态
+// @Description  只有所有者可以创建，新状态排在最后。项目中还没有状态的待办事项如果完成状态与 done 相同，就进入新状态
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int               true  "项目ID"
+// @Param        input  body      CreateStateInput  true  "状态名称和是否算作完成"
+// @Success      200    {object}  models.WorkflowState
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      403    {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404    {object}  map[string]interface{}  "项目不存在"
+// @Router       /projects/{id}/states [post]
+// @Security    BearerAuth
+func (h *ProjectHandler) CreateState(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var input CreateStateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, models.RoleOwner); !ok {
+		return
+	}
+	state := models.WorkflowState{ProjectId: projectID, Name: input.Name, Done: input.Done}
+	if err := h.states.WithContext(c.Request.Context()).CreateState(uid.(uint), &state); err != nil {
+		_ = c.Error(ierr.ErrSystem)
+		return
+	}
+	response.Success(c, state)
+}
+
+// UpdateState godoc
+// @Summary      修改工作流状态
+// @Description  只有所有者可以修改名称、顺序和是否算作完成，修改 done 时处于该状态的待办事项的完成状态随之改变。
+// @Description  其中有待办事项还有未完成的阻塞者时不能改为完成
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int               true  "项目ID"
+// @Param        sid    path      int               true  "状态ID"
+// @Param        input  body      UpdateStateInput  true  "要修改的字段"
+// @Success      200    {object}  models.WorkflowState
+// @Failure      400    {object}  map[string]interface{}  "请求参数错误"
+// @Failure      403    {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404    {object}  map[string]interface{}  "项目或状态不存在"
+// @Failure      409    {object}  map[string]interface{}  "处于该状态的待办事项还有未完成的阻塞者"
+// @Router       /projects/{id}/states/{sid} [put]
+// @Security    BearerAuth
+func (h *ProjectHandler) UpdateState(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	stateID, ok := parseID(c, "sid")
+	if !ok {
+		return
+	}
+	var input UpdateStateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(ierr.ErrInvalidInput)
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, models.RoleOwner); !ok {
+		return
+	}
+	states := h.states.WithContext(c.Request.Context())
+	state, ok := h.state(c, states, projectID, stateID)
+	if !ok {
+		return
+	}
+	fields := map[string]any{}
+	if input.Name != nil {
+		fields["name"] = *input.Name
+	}
+	if input.Done != nil && *input.Done != state.Done {
+		fields["done"] = *input.Done
+	}
+	if input.Position != nil {
+		fields["position"] = *input.Position
+	}
+	if len(fields) > 0 {
+		if err := states.UpdateState(state, fields); err != nil {
+			if errors.Is(err, repository.ErrBlocked) {
+				_ = c.Error(ierr.ErrStateBlocked)
+				return
+			}
+			_ = c.Error(ierr.ErrSystem)
+			return
+		}
+	}
+	response.Success(c, state)
+}
+
+// DeleteState godoc
+// @Summary      删除工作流状态
+// @Description  只有所有者可以删除，需要先把处于该状态的待办事项移动到其他状态。在该状态中停留的记录仍然保留
+// @Tags         projects
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Param        sid  path      int  true  "状态ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}  "不是项目所有者"
+// @Failure      404  {object}  map[string]interface{}  "项目或状态不存在"
+// @Failure      409  {object}  map[string]interface{}  "仍有待办事项处于该状态"
+// @Router       /projects/{id}/states/{sid} [delete]
+// @Security    BearerAuth
+func (h *ProjectHandler) DeleteState(c *gin.Context) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		_ = c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	projectID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	stateID, ok := parseID(c, "sid")
+	if !ok {
+		return
+	}
+	if _, ok := h.member(c, uid.(uint), projectID, models.RoleOwner); !ok {
+		return
+	}
+	states := h.states.WithContext(c.Request.Context())
+	state, ok := h.state(c, states, projectID, stateID)
+	if !ok {
+		return
+	}
+	if err := states.DeleteState(state); err != nil {
+		if errors.Is(err, repository.ErrStateInUse) {
+			_ = c.Error(ierr.ErrStateInUse)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return
+	}
+	response.Success(c, nil)
+}
+
+// state 返回项目中的工作流状态
+func (h *ProjectHandler) state(c *gin.Context, states repository.WorkflowRepository,
+	projectID, stateID uint) (*models.WorkflowState, bool) {
+	state, err := states.GetState(projectID, stateID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.Error(ierr.ErrStateNotFound)
+		} else {
+			_ = c.Error(ierr.ErrSystem)
+		}
+		return nil, false
+	}
+	return state, true
+}
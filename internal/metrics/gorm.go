@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"todolist-api/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// RegisterDB 为数据库注册语句耗时和错误、连接池状态以及待办事项数量等指标
+func RegisterDB(db *gorm.DB, dbName string) error {
+	if err := db.Use(gormPlugin{}); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := Registry.Register(collectors.NewDBStatsCollector(sqlDB, dbName)); err != nil {
+		return err
+	}
+	return Registry.Register(newBusinessCollector(db))
+}
+
+// gormPlugin 通过 GORM 回调记录每条语句的耗时和错误
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	// 紧挨着执行 SQL 的回调注册，使耗时不包含 BeforeSave 等钩子（例如密码哈希）
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	)
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// businessCollector 在每次抓取时从数据库统计待办事项和用户数量
+type businessCollector struct {
+	db    *gorm.DB
+	todos *prometheus.Desc
+	users *prometheus.Desc
+}
+
+func newBusinessCollector(db *gorm.DB) *businessCollector {
+	return &businessCollector{
+		db:    db,
+		todos: prometheus.NewDesc(namespace+"_todos", "Number of todos by status.", []string{"status"}, nil),
+		users: prometheus.NewDesc(namespace+"_users", "Number of registered users.", nil, nil),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.todos
+	ch <- c.users
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db := c.db.WithContext(ctx)
+
+	var rows []struct {
+		Status bool
+		Count  int64
+	}
+	if err := db.Model(&models.Todo{}).Select("status, count(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		slog.Error("failed to count todos for metrics", slog.Any("error", err))
+	} else {
+		counts := map[string]int64{"open": 0, "done": 0}
+		for _, row := range rows {
+			if row.Status {
+				counts["done"] += row.Count
+			} else {
+				counts["open"] += row.Count
+			}
+		}
+		for status, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.todos, prometheus.GaugeValue, float64(count), status)
+		}
+	}
+
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		slog.Error("failed to count users for metrics", slog.Any("error", err))
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(users))
+	}
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "todolist"
+
+// Registry 本服务的指标。不使用 prometheus 的全局注册表，避免依赖库注册的指标混入
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests 按路由模板（例如 /api/v1/todos/:id）、方法和状态码统计的请求数
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration 请求耗时
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// DBQueryDuration 按操作类型和表统计的 GORM 语句耗时
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM statement latency by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// DBQueryErrors 执行失败的 GORM 语句数，不包括记录不存在
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Number of failed GORM statements by operation and table, excluding record not found.",
+	}, []string{"operation", "table"})
+
+	// DBReplicaUp 只读副本是否健康，不健康的副本不会接收查询
+	DBReplicaUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_replica_up",
+		Help:      "Whether a read replica is healthy and receiving queries.",
+	}, []string{"replica"})
+
+	// TodosCreated 创建的待办事项数
+	TodosCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "todos_created_total",
+		Help:      "Number of todos created.",
+	})
+
+	// TodosCompleted 被标记为完成的待办事项数
+	TodosCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "todos_completed_total",
+		Help:      "Number of todos marked as completed.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, DBQueryDuration, DBQueryErrors, DBReplicaUp, TodosCreated, TodosCompleted,
+	)
+}
+
+// Handler 以 Prometheus 文本格式输出所有指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
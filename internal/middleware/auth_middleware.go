@@ -2,13 +2,18 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 	"todolist-api/internal/services"
+	"todolist-api/pkg/config"
+	"todolist-api/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(service *services.AuthService) gin.HandlerFunc {
+// AuthMiddleware 校验 Bearer 令牌，支持登录得到的 JWT 和个人访问令牌。
+// 使用个人访问令牌时会在上下文中设置 scopes，JWT 会话则不受权限范围限制
+func AuthMiddleware(service *services.AuthService, tokens *services.PersonalTokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -21,12 +26,58 @@ func AuthMiddleware(service *services.AuthService) gin.HandlerFunc {
 			return
 		}
 		tokenString := parts[1]
-		claims, err := service.VerifyToken(tokenString)
+		if tokens != nil && services.IsPersonalToken(tokenString) {
+			token, err := tokens.Verify(c.Request.Context(), tokenString, c.ClientIP())
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				return
+			}
+			c.Set("uid", token.UserId)
+			logger.SetUserID(c.Request.Context(), token.UserId)
+			c.Set("scopes", token.ScopeList())
+			c.Next()
+			return
+		}
+		claims, err := service.VerifyToken(c.Request.Context(), tokenString)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
 		c.Set("uid", claims.UserId)
+		logger.SetUserID(c.Request.Context(), claims.UserId)
+		c.Next()
+	}
+}
+
+// RequireScope 要求个人访问令牌具有指定的权限范围，JWT 会话直接放行
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scopes, exists := c.Get("scopes"); exists && !slices.Contains(scopes.([]string), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token does not have the required scope: " + scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession 只允许登录得到的 JWT 会话访问，用于账户和令牌管理等敏感接口
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("scopes"); exists {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot access this endpoint"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin 只允许配置中的管理员访问，需要放在 AuthMiddleware 之后
+func RequireAdmin(cfg *config.AdminConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(cfg.UserIds, c.GetUint("uid")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/services"
+	"todolist-api/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	do := func(uid uint) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("uid", uid) }, RequireAdmin(&config.AdminConfig{UserIds: []uint{1}}))
+		r.GET("/admin/audit", func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit", nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, do(1))
+	assert.Equal(t, http.StatusForbidden, do(2))
+}
+
+// memoryTokenRepository 内存中的 PersonalTokenRepository
+type memoryTokenRepository struct {
+	tokens []*models.PersonalAccessToken
+}
+
+func (r *memoryTokenRepository) Create(token *models.PersonalAccessToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) GetByHash(hash string) (*models.PersonalAccessToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash && !token.DeletedAt.Valid {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) ListByUser(uint) ([]models.PersonalAccessToken, error) {
+	return nil, nil
+}
+
+func (r *memoryTokenRepository) Revoke(uid, id uint) error {
+	r.tokens[id-1].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *memoryTokenRepository) Touch(uint, time.Time, string) error { return nil }
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtCfg := &config.JWTConfig{Secret: "0123456789abcdef0123456789abcdef", ExpireHours: 1}
+	keys, err := services.LoadKeySet(jwtCfg)
+	require.NoError(t, err)
+	auth := services.NewAuthService(jwtCfg, keys, nil)
+	repo := &memoryTokenRepository{}
+	tokens := services.NewPersonalTokenService(repo)
+
+	// 与 routes 中的用法一致
+	r := gin.New()
+	protected := r.Group("", AuthMiddleware(auth, tokens))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	protected.GET("/todos", RequireScope(services.ScopeTodosRead), ok)
+	protected.POST("/todos", RequireScope(services.ScopeTodosWrite), ok)
+	protected.POST("/user/password", RequireSession(), ok)
+	protected.GET("/admin/audit", RequireSession(), RequireAdmin(&config.AdminConfig{UserIds: []uint{1}}), ok)
+	do := func(method, path, authorization string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	user := &models.User{}
+	user.ID = 1
+	session, err := auth.GenerateToken(user)
+	require.NoError(t, err)
+	readOnly, _, err := tokens.Create(1, "read", []string{services.ScopeTodosRead}, nil)
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Minute)
+	expired, _, err := tokens.Create(1, "expired", []string{services.ScopeTodosRead}, &past)
+	require.NoError(t, err)
+	revoked, revokedToken, err := tokens.Create(1, "revoked", []string{services.ScopeTodosRead}, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.Revoke(1, revokedToken.ID))
+
+	t.Run("missing or malformed credentials", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/todos", ""))
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/todos", "Token "+session))
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/todos", "Bearer tdl_unknown"))
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/todos", "Bearer "+expired))
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/todos", "Bearer "+revoked))
+	})
+
+	t.Run("sessions are not limited by scopes", func(t *testing.T) {
+		for _, path := range []string{"/todos", "/admin/audit"} {
+			assert.Equal(t, http.StatusOK, do(http.MethodGet, path, "Bearer "+session), path)
+		}
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/todos", "Bearer "+session))
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/user/password", "Bearer "+session))
+	})
+
+	t.Run("personal tokens are limited to their scopes and cannot manage the account", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/todos", "Bearer "+readOnly))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/todos", "Bearer "+readOnly))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/user/password", "Bearer "+readOnly))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/audit", "Bearer "+readOnly))
+	})
+}
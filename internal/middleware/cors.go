@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"todolist-api/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// originMatcher 判断来源是否允许跨域访问
+type originMatcher struct {
+	any      bool
+	exact    map[string]bool
+	suffixes []wildcardOrigin
+}
+
+// wildcardOrigin https://*.example.com 形式的来源，匹配 example.com 的任意子域名，不包括 example.com 本身
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+func newOriginMatcher(origins []string) *originMatcher {
+	m := &originMatcher{exact: make(map[string]bool, len(origins))}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			m.any = true
+			continue
+		}
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			m.suffixes = append(m.suffixes, wildcardOrigin{scheme: scheme + "://", suffix: "." + host})
+			continue
+		}
+		m.exact[origin] = true
+	}
+	return m
+}
+
+func (m *originMatcher) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.any || m.exact[origin] {
+		return true
+	}
+	for _, w := range m.suffixes {
+		if rest, ok := strings.CutPrefix(origin, w.scheme); ok && strings.HasSuffix(rest, w.suffix) &&
+			len(rest) > len(w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type cors struct {
+	cfg     *config.CORSConfig
+	origins atomic.Pointer[originMatcher]
+}
+
+func newCORS(cfg *config.CORSConfig) *cors {
+	c := &cors{cfg: cfg}
+	c.setOrigins(cfg.AllowedOrigins)
+	return c
+}
+
+func (c *cors) setOrigins(origins []string) {
+	c.origins.Store(newOriginMatcher(origins))
+}
+
+// CORS 允许配置的来源跨域访问 API，并直接响应预检请求。
+// 允许的来源通过 config.CORSOrigins 订阅，修改配置文件后立即生效
+func CORS(cfg *config.CORSConfig) gin.HandlerFunc {
+	c := newCORS(cfg)
+	config.CORSOrigins.Subscribe(c.setOrigins)
+	return c.handle
+}
+
+func (c *cors) handle(ctx *gin.Context) {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" {
+		ctx.Next()
+		return
+	}
+	// 响应内容随 Origin 变化，避免缓存把一个来源的响应返回给另一个来源
+	ctx.Writer.Header().Add("Vary", "Origin")
+	preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+	origins := c.origins.Load()
+	if !origins.allowed(origin) {
+		if preflight {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		// 不返回 CORS 响应头，由浏览器拦截响应
+		ctx.Next()
+		return
+	}
+
+	header := ctx.Writer.Header()
+	if origins.any && !c.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(c.cfg.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+		}
+		ctx.Next()
+		return
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(c.cfg.AllowedMethods, ", "))
+	if len(c.cfg.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.cfg.AllowedHeaders, ", "))
+	}
+	if c.cfg.MaxAgeSeconds > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(c.cfg.MaxAgeSeconds))
+	}
+	ctx.AbortWithStatus(http.StatusNoContent)
+}
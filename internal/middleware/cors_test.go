@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todolist-api/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginMatcher(t *testing.T) {
+	m := newOriginMatcher([]string{"https://app.example.com", "https://*.example.org/"})
+	assert.True(t, m.allowed("https://app.example.com"))
+	assert.True(t, m.allowed("https://a.b.example.org"))
+	assert.False(t, m.allowed("https://example.org"))
+	assert.False(t, m.allowed("http://a.example.org"))
+	assert.False(t, m.allowed("https://evilexample.org"))
+	assert.False(t, m.allowed("https://app.example.com.evil.com"))
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := newCORS(&config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAgeSeconds:  600,
+	})
+	r := gin.New()
+	r.Use(c.handle)
+	r.GET("/todos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/todos", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = do(http.MethodGet, "https://app.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodOptions, "https://evil.com").Code)
+	w = do(http.MethodGet, "https://evil.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 修改允许的来源后立即生效
+	c.setOrigins([]string{"https://evil.com"})
+	assert.Equal(t, http.StatusNoContent, do(http.MethodOptions, "https://evil.com").Code)
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeaders(&config.SecurityHeadersConfig{HSTSMaxAgeSeconds: 60, FrameOptions: "DENY",
+		ContentSecurityPolicy: "default-src 'none'", SwaggerContentSecurityPolicy: "default-src 'self'"}))
+	r.GET("/*any", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "default-src 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+
+	req := httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "max-age=60", w.Header().Get("Strict-Transport-Security"))
+}
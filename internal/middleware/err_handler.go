@@ -2,22 +2,30 @@ package middleware
 
 import (
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"todolist-api/pkg/ierr"
 	"todolist-api/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorHandler 把处理过程中记录的错误和 panic 转换为统一的错误响应。
+// 存在 trace ID 时通过 X-Trace-ID 响应头返回，便于按错误响应查找调用链
 func ErrorHandler() gin.HandlerFunc {
 	return func(context *gin.Context) {
+		if traceID := response.TraceID(context); traceID != "" {
+			context.Header("X-Trace-ID", traceID)
+		}
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("panic recovered: %v", err)
+				slog.ErrorContext(context.Request.Context(), "panic recovered",
+					slog.Any("panic", err), slog.String("stack", string(debug.Stack())))
 				if !context.Writer.Written() {
 					context.JSON(http.StatusInternalServerError, gin.H{
-						"error": "Internal Server Error",
+						"error":    "Internal Server Error",
+						"trace_id": response.TraceID(context),
 					})
 				}
 			}
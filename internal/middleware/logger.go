@@ -1,30 +1,46 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"time"
+	"todolist-api/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// probePaths 健康检查探针请求频繁，成功时只在 debug 级别记录
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true}
+
+// Logger 每个请求结束后记录一条访问日志，5xx 记为 error，4xx 记为 warn
 func Logger() gin.HandlerFunc {
-	return func(context *gin.Context) {
+	return func(c *gin.Context) {
 		startTime := time.Now()
-		context.Next()
-		endTime := time.Now()
-		latencyTime := endTime.Sub(startTime)
-		reqMethod := context.Request.Method
-		reqUri := context.Request.RequestURI
-		statusCode := context.Writer.Status()
-		clientIp := context.ClientIP()
-		// 格式化日志输出
-		log.Printf("[GIN] %v | %3d | %13v | %15s | %-7s %s\n",
-			endTime.Format("2006/01/02 - 15:04:05"),
-			statusCode,
-			latencyTime,
-			clientIp,
-			reqMethod,
-			reqUri,
-		)
+		c.Next()
+		statusCode := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case statusCode >= 500:
+			level = slog.LevelError
+		case statusCode >= 400:
+			level = slog.LevelWarn
+		case probePaths[c.Request.URL.Path]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", statusCode),
+			slog.Duration("latency", time.Since(startTime)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		}
+		// 查询参数中可能带有授权码等敏感信息
+		if query := logger.RedactQuery(c.Request.URL.RawQuery); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.Last().Error()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
 	}
 }
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+	"todolist-api/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 记录请求数和耗时。使用路由模板作为标签，避免 /todos/1、/todos/2 这类路径产生大量时间序列
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"todolist-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 按路由组限流。已认证的请求按 uid 计数，公开路由按客户端 IP 计数，
+// 因此在受保护的路由组中需要注册在 AuthMiddleware 之后
+func RateLimit(limiter *ratelimit.Limiter, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		key := "ip:" + c.ClientIP()
+		if uid, exists := c.Get("uid"); exists {
+			key = fmt.Sprintf("uid:%v", uid)
+		}
+		res, err := limiter.Take(c.Request.Context(), group, key)
+		if err != nil {
+			// 计数存储不可用时放行，避免限流组件故障导致整个 API 不可用
+			slog.ErrorContext(c.Request.Context(), "rate limit store error", slog.Any("error", err))
+			c.Next()
+			return
+		}
+		if res.Limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset.Seconds())))
+		}
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(s float64) int {
+	return int(math.Ceil(s))
+}
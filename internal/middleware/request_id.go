@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"todolist-api/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID 只接受长度合理、不含特殊字符的请求ID，避免日志注入
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+// RequestID 沿用客户端或网关传入的 X-Request-ID，没有时生成一个新的，
+// 并把它放到请求的 context 中，使这个请求的所有日志都带上 request_id
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
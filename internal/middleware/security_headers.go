@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"todolist-api/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SwaggerPathPrefix Swagger UI 的路由前缀，这些页面使用单独的 CSP
+const SwaggerPathPrefix = "/swagger/"
+
+// SecurityHeaders 添加安全相关的响应头。Strict-Transport-Security 只在 HTTPS 请求
+// （直接 TLS 或反向代理通过 X-Forwarded-Proto 标记）上返回
+func SecurityHeaders(cfg *config.SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAgeSeconds > 0 {
+		hsts = fmt.Sprintf("max-age=%d", cfg.HSTSMaxAgeSeconds)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+		if cfg.FrameOptions != "" {
+			header.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		csp := cfg.ContentSecurityPolicy
+		if strings.HasPrefix(c.Request.URL.Path, SwaggerPathPrefix) {
+			csp = cfg.SwaggerContentSecurityPolicy
+		}
+		if csp != "" {
+			header.Set("Content-Security-Policy", csp)
+		}
+		if hsts != "" && (c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https") {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Attachment 待办事项的附件，文件内容保存在 BlobStore 中
+type Attachment struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `json:"created_at"`
+	// TodoId 附件所属的待办事项
+	TodoId uint `gorm:"not null;index" json:"todo_id" example:"1"`
+	// UserId 上传附件的用户
+	UserId uint `gorm:"not null" json:"uid" example:"1"`
+	// Filename 上传时的文件名
+	Filename string `gorm:"not null" json:"filename" example:"screenshot.png"`
+	// ContentType 根据文件内容识别的 MIME 类型
+	ContentType string `gorm:"not null" json:"content_type" example:"image/png"`
+	// Size 文件大小（字节）
+	Size int64 `gorm:"not null" json:"size" example:"48213"`
+	// StorageKey 文件在 BlobStore 中的 key
+	StorageKey string `gorm:"not null;uniqueIndex" json:"-"`
+}
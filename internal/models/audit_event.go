@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// 审计事件的实体类型
+const (
+	AuditEntityTodo = "todo"
+	AuditEntityUser = "user"
+)
+
+// 审计事件的操作
+const (
+	AuditTodoCreate  = "todo.create"
+	AuditTodoUpdate  = "todo.update"
+	AuditTodoDelete  = "todo.delete"
+	AuditTodoAssign  = "todo.assign"
+	AuditTodoUndo    = "todo.undo"
+	AuditTodoMove    = "todo.move"
+	AuditTodoState   = "todo.state"
+	AuditTodoBlock   = "todo.block"
+	AuditTodoUnblock = "todo.unblock"
+
+	AuditUserRegister              = "user.register"
+	AuditUserPasswordChange        = "user.password_change"
+	AuditUserPasswordResetRequest  = "user.password_reset_request"
+	AuditUserPasswordReset         = "user.password_reset"
+	AuditUserTOTPEnroll            = "user.2fa_enroll"
+	AuditUserTOTPEnable            = "user.2fa_enable"
+	AuditUserTOTPDisable           = "user.2fa_disable"
+	AuditUserRecoveryCodesGenerate = "user.recovery_codes_generate"
+	AuditUserTokenCreate           = "user.token_create"
+	AuditUserTokenRevoke           = "user.token_revoke"
+	AuditUserIdentityLink          = "user.identity_link"
+)
+
+// AuditEvent 一次修改操作的审计记录，只追加、不修改，超过保留期后由后台任务删除
+type AuditEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	// ActorId 执行操作的用户，未登录的操作（如重置密码）为空
+	ActorId *uint `gorm:"index" json:"actor_id" example:"1"`
+	// Action 操作，例如 todo.delete
+	Action string `gorm:"not null;index" json:"action" example:"todo.delete"`
+	// EntityType、EntityId 被操作的对象
+	EntityType string `gorm:"not null;index:idx_audit_entity" json:"entity_type" example:"todo"`
+	EntityId   uint   `gorm:"not null;index:idx_audit_entity" json:"entity_id" example:"1"`
+	// Changes 变化的字段，创建时 before 为 null，删除时 after 为 null
+	Changes map[string]AuditChange `gorm:"type:text;serializer:json" json:"changes,omitempty"`
+	// IP 客户端地址
+	IP string `json:"ip" example:"203.0.113.7"`
+	// RequestId 请求 ID，用于关联日志
+	RequestId string `gorm:"index" json:"request_id" example:"8f14e45f-ceea-467f-a0e6-4b8f5c3b1f2a"`
+}
+
+// AuditChange 一个字段修改前后的值
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Comment 待办事项下的评论
+type Comment struct {
+	gorm.Model
+	// TodoId 评论所属的待办事项
+	TodoId uint `gorm:"not null;index" json:"todo_id" example:"1"`
+	// UserId 评论的作者
+	UserId uint `gorm:"not null" json:"uid" example:"1"`
+	// Body 评论内容，可以使用 @用户名 提及其他用户
+	Body string `gorm:"type:text;not null" json:"body" example:"@janedoe 记得买低脂的"`
+	// Edited 评论发布后是否被修改过
+	Edited bool `gorm:"not null;default:false" json:"edited" example:"false"`
+	// Author 作者信息
+	Author *User `gorm:"foreignKey:UserId" json:"-"`
+	// Mentions 评论中提及的用户
+	Mentions []CommentMention `json:"-"`
+}
+
+// CommentMention 评论中提及的用户，只记录有权查看该待办事项的用户
+type CommentMention struct {
+	ID        uint `gorm:"primarykey"`
+	CommentId uint `gorm:"not null;uniqueIndex:idx_comment_mention"`
+	UserId    uint `gorm:"not null;uniqueIndex:idx_comment_mention;index"`
+	User      *User
+}
+
+// mentionPattern 匹配 @用户名，@ 前面不能是字母数字（排除邮箱地址）
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.-]+)`)
+
+// ParseMentions 返回评论中提及的用户名，按第一次出现的顺序去重（不区分大小写），
+// 末尾的句点不属于用户名
+func ParseMentions(body string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.TrimRight(match[1], ".")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@alice please check", []string{"alice"}},
+		{"cc @bob.smith, @carol-x and @bob.smith.", []string{"bob.smith", "carol-x"}},
+		{"mail me at john@example.com", nil},
+		{"(@Dave) and @dave again", []string{"Dave"}},
+		{"@@eve @ frank", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseMentions(tt.body), tt.body)
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"container/heap"
+	"time"
+)
+
+// TodoDependency 待办事项之间的阻塞关系：TodoId 要等 BlockerId 完成之后才能完成。
+// 两者必须在同一个列表中（同一个项目或同一个用户的个人待办事项），并且不能形成环
+type TodoDependency struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `json:"created_at"`
+	// TodoId 被阻塞的待办事项
+	TodoId uint `gorm:"not null;uniqueIndex:idx_todo_dependency" json:"todo_id" example:"2"`
+	// BlockerId 阻塞它的待办事项
+	BlockerId uint `gorm:"not null;uniqueIndex:idx_todo_dependency;index" json:"blocker_id" example:"1"`
+	// UserId 添加该关系的用户
+	UserId uint `gorm:"not null" json:"uid" example:"1"`
+}
+
+// CreatesCycle 判断在 deps 中加入“todoID 被 blockerID 阻塞”之后是否会形成环，
+// 即 blockerID 本身是否直接或间接地被 todoID 阻塞
+func CreatesCycle(deps []TodoDependency, todoID, blockerID uint) bool {
+	blockers := make(map[uint][]uint)
+	for _, d := range deps {
+		blockers[d.TodoId] = append(blockers[d.TodoId], d.BlockerId)
+	}
+	visited := map[uint]bool{}
+	stack := []uint{blockerID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == todoID {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, blockers[id]...)
+	}
+	return false
+}
+
+// TopologicalOrder 按拓扑顺序返回 ids：阻塞者排在被它阻塞的待办事项之前，
+// 没有先后要求的保持在 ids 中的顺序。只考虑两端都在 ids 中的关系，存在环时返回 false
+func TopologicalOrder(ids []uint, deps []TodoDependency) ([]uint, bool) {
+	index := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	blocked := make([][]int, len(ids))
+	waiting := make([]int, len(ids))
+	for _, d := range deps {
+		todo, ok := index[d.TodoId]
+		blocker, ok2 := index[d.BlockerId]
+		if ok && ok2 {
+			blocked[blocker] = append(blocked[blocker], todo)
+			waiting[todo]++
+		}
+	}
+	ready := &indexHeap{}
+	for i := range ids {
+		if waiting[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+	order := make([]uint, 0, len(ids))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		order = append(order, ids[i])
+		for _, j := range blocked[i] {
+			if waiting[j]--; waiting[j] == 0 {
+				heap.Push(ready, j)
+			}
+		}
+	}
+	return order, len(order) == len(ids)
+}
+
+// indexHeap 下标的最小堆，用于在拓扑排序中保持原来的顺序
+type indexHeap []int
+
+func (h indexHeap) Len() int           { return len(h) }
+func (h indexHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatesCycle(t *testing.T) {
+	// 3 被 2 阻塞，2 被 1 阻塞
+	deps := []TodoDependency{{TodoId: 3, BlockerId: 2}, {TodoId: 2, BlockerId: 1}}
+
+	assert.True(t, CreatesCycle(deps, 1, 3))
+	assert.True(t, CreatesCycle(deps, 1, 2))
+	assert.True(t, CreatesCycle(deps, 4, 4))
+	assert.False(t, CreatesCycle(deps, 3, 1))
+	assert.False(t, CreatesCycle(deps, 4, 3))
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	deps := []TodoDependency{{TodoId: 1, BlockerId: 3}, {TodoId: 3, BlockerId: 4}, {TodoId: 2, BlockerId: 9}}
+
+	order, ok := TopologicalOrder([]uint{1, 2, 3, 4, 5}, deps)
+	assert.True(t, ok)
+	assert.Equal(t, []uint{2, 4, 3, 1, 5}, order, "unrelated todos keep their order")
+
+	_, ok = TopologicalOrder([]uint{1, 2}, []TodoDependency{{TodoId: 1, BlockerId: 2}, {TodoId: 2, BlockerId: 1}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken 找回密码的一次性令牌，只保存哈希值
+type PasswordResetToken struct {
+	gorm.Model
+	// UserId 令牌所属的用户ID
+	UserId uint `gorm:"not null;index" json:"uid"`
+	// TokenHash 令牌的 SHA-256 哈希
+	TokenHash string `gorm:"not null;uniqueIndex" json:"-"`
+	// ExpiresAt 过期时间
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	// UsedAt 使用时间，为空表示尚未使用
+	UsedAt *time.Time `json:"used_at"`
+}
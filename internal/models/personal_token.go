@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken 个人访问令牌，供脚本和第三方集成使用，只保存哈希值。
+// 撤销令牌即软删除该记录
+type PersonalAccessToken struct {
+	gorm.Model
+	// UserId 令牌所属的用户ID
+	UserId uint `gorm:"not null;index" json:"uid"`
+	// Name 令牌名称，便于用户区分用途
+	Name string `gorm:"not null" json:"name" example:"ci-script"`
+	// TokenHash 令牌的 SHA-256 哈希
+	TokenHash string `gorm:"not null;uniqueIndex" json:"-"`
+	// Prefix 令牌明文的前几位，便于用户在列表中辨认
+	Prefix string `gorm:"not null" json:"prefix" example:"tdl_3f2a9c"`
+	// Scopes 以逗号分隔的权限范围，例如 todos:read,todos:write
+	Scopes string `gorm:"not null" json:"-"`
+	// ExpiresAt 过期时间，为空表示永不过期
+	ExpiresAt *time.Time `json:"expires_at"`
+	// LastUsedAt 最近一次使用的时间
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// LastUsedIP 最近一次使用的客户端IP
+	LastUsedIP string `json:"last_used_ip"`
+}
+
+// ScopeList 返回令牌的权限范围列表
+func (t *PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
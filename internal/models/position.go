@@ -0,0 +1,23 @@
+package models
+
+// PositionStep 新建和重新分配位置时相邻待办事项之间的间隔
+const PositionStep = 1024.0
+
+// minPositionGap 相邻位置的差小于该值时不再取中点，需要重新分配整个列表的位置
+const minPositionGap = 1e-6
+
+// PositionBetween 返回排在 prev 和 next 之间的位置，nil 表示列表的开头或结尾。
+// 两者之间已经没有足够的间隔时返回 false
+func PositionBetween(prev, next *float64) (float64, bool) {
+	switch {
+	case prev == nil && next == nil:
+		return 0, true
+	case prev == nil:
+		return *next - PositionStep, true
+	case next == nil:
+		return *prev + PositionStep, true
+	case *next-*prev < minPositionGap:
+		return 0, false
+	}
+	return *prev + (*next-*prev)/2, true
+}
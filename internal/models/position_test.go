@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositionBetween(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	pos, ok := PositionBetween(nil, nil)
+	assert.True(t, ok)
+	assert.Zero(t, pos)
+	pos, _ = PositionBetween(nil, f(10))
+	assert.Equal(t, 10-PositionStep, pos)
+	pos, _ = PositionBetween(f(10), nil)
+	assert.Equal(t, 10+PositionStep, pos)
+	pos, _ = PositionBetween(f(1), f(2))
+	assert.Equal(t, 1.5, pos)
+
+	_, ok = PositionBetween(f(1), f(1))
+	assert.False(t, ok)
+	_, ok = PositionBetween(f(2), f(1))
+	assert.False(t, ok)
+
+	// 反复插入到同一个间隔中，最终需要重新分配
+	prev, next := 0.0, PositionStep
+	for i := 0; ; i++ {
+		pos, ok := PositionBetween(&prev, &next)
+		if !ok {
+			assert.Greater(t, i, 20)
+			break
+		}
+		assert.True(t, prev < pos && pos < next)
+		next = pos
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 项目成员的角色
+const (
+	// RoleViewer 只能查看项目中的待办事项
+	RoleViewer = "viewer"
+	// RoleEditor 可以创建、修改和删除项目中的待办事项
+	RoleEditor = "editor"
+	// RoleOwner 在编辑者的基础上还可以邀请、移除成员和修改成员的角色
+	RoleOwner = "owner"
+)
+
+// 项目成员的邀请状态
+const (
+	MemberPending  = "pending"
+	MemberAccepted = "accepted"
+	MemberDeclined = "declined"
+)
+
+// WriteRoles 可以修改项目中待办事项的角色
+var WriteRoles = []string{RoleEditor, RoleOwner}
+
+// ValidRole 判断是否为有效的成员角色
+func ValidRole(role string) bool {
+	return role == RoleViewer || role == RoleEditor || role == RoleOwner
+}
+
+// Project 共享的待办清单（项目），成员可以按角色查看或修改其中的待办事项
+type Project struct {
+	gorm.Model
+	// Name 项目名称
+	Name string `gorm:"not null" json:"name" example:"家庭采购"`
+	// UserId 创建该项目的用户ID，创建者同时是项目的第一个所有者
+	UserId uint `gorm:"not null;index" json:"uid" example:"1"`
+}
+
+// ProjectMember 项目成员及邀请。邀请即一条 pending 状态的记录，被邀请的用户接受后成为成员。
+// 移除成员时直接删除记录（不使用软删除），以便之后可以重新邀请
+type ProjectMember struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ProjectId 所属项目
+	ProjectId uint     `gorm:"not null;uniqueIndex:idx_project_member" json:"project_id" example:"1"`
+	Project   *Project `json:"project,omitempty"`
+	// UserId 成员（被邀请的用户）的ID
+	UserId uint  `gorm:"not null;uniqueIndex:idx_project_member;index" json:"uid" example:"2"`
+	User   *User `json:"user,omitempty"`
+	// Role viewer、editor 或 owner
+	Role string `gorm:"not null" json:"role" example:"editor"`
+	// Status pending、accepted 或 declined
+	Status string `gorm:"not null;index" json:"status" example:"pending"`
+	// InvitedBy 发出邀请的用户ID，项目创建者的记录为 0
+	InvitedBy uint `json:"invited_by" example:"1"`
+}
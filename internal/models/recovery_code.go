@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode 两步验证的一次性恢复码，只保存哈希值
+type RecoveryCode struct {
+	gorm.Model
+	// UserId 恢复码所属的用户ID
+	UserId uint `gorm:"not null;index" json:"uid"`
+	// CodeHash 恢复码的 SHA-256 哈希
+	CodeHash string `gorm:"not null" json:"-"`
+	// UsedAt 使用时间，为空表示尚未使用
+	UsedAt *time.Time `json:"used_at"`
+}
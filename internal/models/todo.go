@@ -7,8 +7,21 @@ type Todo struct {
 	gorm.Model
 	// Title 待办事项的标题
 	Title string `gorm:"not null" json:"title" example:"完成项目文档"`
-	// Status 待办事项的完成状态，false表示未完成，true表示已完成
+	// Status 待办事项的完成状态，false表示未完成，true表示已完成。
+	// 项目定义了工作流状态时由所处状态的 done 决定
 	Status bool `gorm:"default:false" json:"status" example:"false"`
 	// UserId 创建该待办事项的用户ID
 	UserId uint `gorm:"not null" json:"uid" example:"1"`
+	// ProjectId 所属的共享项目，为空表示只属于创建者的个人待办事项
+	ProjectId *uint `gorm:"index" json:"project_id,omitempty" example:"1"`
+	// AssigneeId 负责该待办事项的用户，可以是任何有权查看该待办事项的用户，为空表示未指派
+	AssigneeId *uint `gorm:"index" json:"assignee_id" example:"2"`
+	// StateId 所处的工作流状态，只有定义了工作流状态的项目中的待办事项才有
+	StateId *uint `gorm:"index" json:"state_id,omitempty" example:"2"`
+	// Position 手动排序的位置，同一个列表（同一个项目或同一个用户的个人待办事项）中按从小到大排列
+	Position float64 `gorm:"not null;default:0;index" json:"position" example:"1024"`
+	// CommentCount 评论数量，查询时统计，不保存在表中
+	CommentCount int64 `gorm:"-" json:"comment_count" example:"3"`
+	// Blocked 是否还有未完成的待办事项阻塞它，查询时计算，不保存在表中
+	Blocked bool `gorm:"-" json:"blocked" example:"false"`
 }
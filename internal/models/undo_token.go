@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 可以撤销的操作
+const (
+	UndoDelete = "delete"
+	UndoStatus = "status"
+)
+
+// UndoToken 撤销待办事项操作的一次性令牌，只保存哈希值
+type UndoToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// UserId 执行操作的用户，只有该用户可以使用令牌
+	UserId uint `gorm:"not null;index" json:"uid"`
+	// TokenHash 令牌的 SHA-256 哈希
+	TokenHash string `gorm:"not null;uniqueIndex" json:"-"`
+	// Action 被撤销的操作：delete 或 status
+	Action string `gorm:"not null" json:"action"`
+	// Snapshot 操作之前的待办事项，撤销时恢复为这些状态。目前只有单个待办事项的操作，
+	// 还没有批量接口，保存为列表是为了以后的批量操作可以用一个令牌撤销
+	Snapshot []Todo `gorm:"type:text;serializer:json" json:"-"`
+	// ExpiresAt 过期时间
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	// UsedAt 使用时间，为空表示尚未使用
+	UsedAt *time.Time `json:"used_at"`
+}
@@ -12,6 +12,16 @@ type User struct {
 	Username string `gorm:"unique;not null" json:"username" example:"johndoe"`
 	// Password 用户密码，在JSON中不显示，存储为哈希值
 	Password string `gorm:"not null" json:"-"`
+	// Email 邮箱，用于找回密码，可以为空
+	Email *string `gorm:"uniqueIndex" json:"email,omitempty" example:"john@example.com"`
+	// TokenVersion 令牌版本，修改或重置密码时递增，使之前签发的令牌全部失效
+	TokenVersion uint `gorm:"not null;default:0" json:"-"`
+	// TOTPSecret 两步验证的 TOTP 密钥（Base32），确认绑定之前处于待启用状态
+	TOTPSecret string `json:"-"`
+	// TOTPEnabled 是否已启用两步验证
+	TOTPEnabled bool `gorm:"default:false" json:"totp_enabled"`
+	// TOTPLastStep 最近一次验证通过的 TOTP 时间步，用于防止验证码重放
+	TOTPLastStep int64 `json:"-"`
 	// Todos 该用户创建的所有待办事项
 	Todos []Todo `json:"todos,omitempty"`
 }
@@ -22,12 +32,21 @@ func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 	// 1. 如果是新记录 (ID为0)
 	// 2. 或者，如果是更新记录且 Password 字段被修改了
 	if u.ID == 0 || tx.Statement.Changed("Password") {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		hashedPassword, err := HashPassword(u.Password)
 		if err != nil {
 			return err
 		}
 		// 在这里，明文密码被替换成了哈希值
-		u.Password = string(hashedPassword)
+		u.Password = hashedPassword
 	}
 	return
 }
+
+// HashPassword 使用 bcrypt 哈希明文密码
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity 本地用户与外部身份提供方账户的关联
+type UserIdentity struct {
+	gorm.Model
+	// UserId 关联的本地用户ID
+	UserId uint `gorm:"not null;index" json:"uid"`
+	// Issuer 身份提供方的 issuer
+	Issuer string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"issuer"`
+	// Subject 用户在身份提供方的唯一标识（ID Token 中的 sub）
+	Subject string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"subject"`
+	// Email 身份提供方返回的邮箱，仅作记录
+	Email string `json:"email"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowState 项目自定义的工作流状态（看板中的一列），例如 Backlog、In Progress、Review、Done。
+// 待办事项的完成状态由所处的工作流状态决定。删除时使用软删除，保留在该状态中停留的记录
+type WorkflowState struct {
+	ID        uint           `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	// ProjectId 所属项目
+	ProjectId uint `gorm:"not null;index" json:"project_id" example:"1"`
+	// Name 状态名称
+	Name string `gorm:"not null" json:"name" example:"In Progress"`
+	// Done 处于该状态的待办事项是否算作已完成
+	Done bool `gorm:"not null;default:false" json:"done" example:"false"`
+	// Position 在项目中的顺序，从小到大排列
+	Position int `gorm:"not null;default:0" json:"position" example:"1"`
+}
+
+// TodoStateTransition 待办事项进入一个工作流状态的记录，LeftAt 为空表示仍处于该状态。
+// 用于统计待办事项在每个状态中停留的时间
+type TodoStateTransition struct {
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+	// TodoId 待办事项
+	TodoId uint `gorm:"not null;index" json:"todo_id" example:"1"`
+	// StateId 进入的状态，状态被删除后仍然保留，State 中可以看到删除时间
+	StateId uint           `gorm:"not null;index" json:"state_id" example:"2"`
+	State   *WorkflowState `json:"state,omitempty"`
+	// UserId 执行移动的用户
+	UserId    uint       `gorm:"not null" json:"uid" example:"1"`
+	EnteredAt time.Time  `gorm:"not null" json:"entered_at"`
+	LeftAt    *time.Time `json:"left_at"`
+}
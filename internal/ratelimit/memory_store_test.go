@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	rule := Rule{Rate: 1, Burst: 3}
+	ctx := context.Background()
+
+	t.Run("Burst then reject", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			res, err := store.Take(ctx, "a", rule)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 3, res.Limit)
+			assert.Equal(t, i, res.Remaining)
+		}
+		res, _ := store.Take(ctx, "a", rule)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.Equal(t, 3*time.Second, res.Reset)
+	})
+
+	t.Run("Refill over time", func(t *testing.T) {
+		now = now.Add(1500 * time.Millisecond)
+		res, _ := store.Take(ctx, "a", rule)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+	})
+
+	t.Run("Keys are independent", func(t *testing.T) {
+		res, _ := store.Take(ctx, "b", rule)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2, res.Remaining)
+	})
+
+	t.Run("Full buckets are swept", func(t *testing.T) {
+		now = now.Add(2 * sweepInterval)
+		_, _ = store.Take(ctx, "c", rule)
+		assert.Len(t, store.buckets, 1)
+	})
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript 在 Redis 中原子地补充并扣减令牌
+// KEYS[1]: 桶的 key；ARGV: rate, burst, 当前时间（毫秒）
+// 返回 {是否允许, 剩余令牌数}，令牌数以字符串返回以避免 Lua 数字被截断为整数
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+local elapsed = math.max(0, now - ts) / 1000
+tokens = math.min(burst, tokens + elapsed * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore 基于 Redis 的令牌桶存储，多个副本可以共享同一份计数
+// 任何实现了 redis.Scripter 的客户端（单机、集群、哨兵，或兼容 Redis 协议的服务）都可以使用
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+// Close 关闭底层的 Redis 连接
+func (s *RedisStore) Close() error {
+	if c, ok := s.client.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	vals, err := tokenBucketScript.Run(ctx, s.client, []string{s.prefix + key},
+		rule.Rate, rule.Burst, s.now().UnixMilli()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := vals[0].(int64)
+	str, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(allowed == 1, tokens, rule), nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRedisStore 需要一个 Redis，例如 docker compose --profile redis up -d redis 启动的实例：
+// REDIS_ADDR=localhost:6379 go test ./internal/ratelimit
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	ctx := context.Background()
+	newClient := func() *redis.Client {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	}
+	client := newClient()
+	require.NoError(t, client.Ping(ctx).Err())
+
+	// 每次运行使用不同的前缀，避免受到上一次运行留下的桶影响
+	prefix := "ratelimit-test:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+	t.Cleanup(func() {
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			_ = client.Del(ctx, keys...).Err()
+		}
+	})
+	now := time.Now()
+	clock := func() time.Time { return now }
+	store := NewRedisStore(client, prefix)
+	store.now = clock
+	rule := Rule{Rate: 1, Burst: 3}
+
+	t.Run("Burst then reject", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			res, err := store.Take(ctx, "a", rule)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 3, res.Limit)
+			assert.Equal(t, i, res.Remaining)
+		}
+		res, err := store.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.Equal(t, 3*time.Second, res.Reset)
+	})
+
+	t.Run("Refill over time", func(t *testing.T) {
+		now = now.Add(1500 * time.Millisecond)
+		res, err := store.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		// 剩下半个令牌，再过半秒才能取到下一个
+		res, err = store.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	})
+
+	t.Run("Bucket expires once it would be full", func(t *testing.T) {
+		// 还差 2.5 个令牌补满，再留 1 秒余量
+		ttl, err := client.PTTL(ctx, prefix+"a").Result()
+		require.NoError(t, err)
+		assert.Greater(t, ttl, 3*time.Second)
+		assert.LessOrEqual(t, ttl, 3500*time.Millisecond)
+
+		_, err = store.Take(ctx, "fresh", Rule{Rate: 10, Burst: 10})
+		require.NoError(t, err)
+		ttl, err = client.PTTL(ctx, prefix+"fresh").Result()
+		require.NoError(t, err)
+		assert.Greater(t, ttl, time.Second)
+		assert.LessOrEqual(t, ttl, 1100*time.Millisecond)
+	})
+
+	t.Run("Instances share buckets", func(t *testing.T) {
+		other := NewRedisStore(newClient(), prefix)
+		other.now = clock
+		res, err := other.Take(ctx, "a", rule)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed, "the bucket drained by the first instance is seen by the second")
+
+		res, err = other.Take(ctx, "b", rule)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		res, err = store.Take(ctx, "b", rule)
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Remaining)
+	})
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentRepository 附件的元数据，文件内容由 BlobStore 保存。调用方负责检查待办事项的权限
+type AttachmentRepository interface {
+	WithContext(ctx context.Context) AttachmentRepository
+
+	Create(attachment *models.Attachment) error
+	// List 按上传时间顺序返回待办事项的附件
+	List(todoID uint) ([]models.Attachment, error)
+	// GetById 返回待办事项下的附件，不存在时返回 gorm.ErrRecordNotFound
+	GetById(todoID, id uint) (*models.Attachment, error)
+	Delete(attachment *models.Attachment) error
+	// ListByTodos 返回这些待办事项的全部附件，包括已删除的待办事项
+	ListByTodos(todoIDs []uint) ([]models.Attachment, error)
+}
+
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+func (r *attachmentRepository) WithContext(ctx context.Context) AttachmentRepository {
+	return &attachmentRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *attachmentRepository) Create(attachment *models.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+func (r *attachmentRepository) List(todoID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("todo_id = ?", todoID).Order("created_at, id").Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *attachmentRepository) GetById(todoID, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.db.Where("todo_id = ?", todoID).First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *attachmentRepository) Delete(attachment *models.Attachment) error {
+	return r.db.Delete(attachment).Error
+}
+
+func (r *attachmentRepository) ListByTodos(todoIDs []uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if len(todoIDs) == 0 {
+		return attachments, nil
+	}
+	err := r.db.Where("todo_id IN ?", todoIDs).Find(&attachments).Error
+	return attachments, err
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
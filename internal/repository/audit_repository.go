@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository 审计事件只能追加和按保留期删除，不提供修改的方法
+type AuditRepository interface {
+	WithContext(ctx context.Context) AuditRepository
+
+	Create(event *models.AuditEvent) error
+	// List 按时间倒序返回一页符合条件的事件和总数
+	List(filter AuditFilter, offset, limit int) ([]models.AuditEvent, int64, error)
+	// DeleteBefore 删除 before 之前的事件，返回删除的数量
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// AuditFilter 审计事件的过滤条件，零值表示不过滤
+type AuditFilter struct {
+	ActorId    *uint
+	Action     string
+	EntityType string
+	EntityId   *uint
+	// From、To 时间范围，包含 From，不包含 To
+	From *time.Time
+	To   *time.Time
+}
+
+func (f AuditFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.ActorId != nil {
+		db = db.Where("actor_id = ?", *f.ActorId)
+	}
+	if f.Action != "" {
+		db = db.Where("action = ?", f.Action)
+	}
+	if f.EntityType != "" {
+		db = db.Where("entity_type = ?", f.EntityType)
+	}
+	if f.EntityId != nil {
+		db = db.Where("entity_id = ?", *f.EntityId)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	return db
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditRepository) WithContext(ctx context.Context) AuditRepository {
+	return &auditRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *auditRepository) Create(event *models.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *auditRepository) List(filter AuditFilter, offset, limit int) ([]models.AuditEvent, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.AuditEvent{}).Scopes(filter.scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []models.AuditEvent
+	err := r.db.Scopes(filter.scope).Order("created_at desc, id desc").Offset(offset).Limit(limit).
+		Find(&events).Error
+	return events, total, err
+}
+
+func (r *auditRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&models.AuditEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
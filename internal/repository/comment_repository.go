@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CommentRepository 待办事项的评论。调用方负责检查用户能否查看对应的待办事项
+type CommentRepository interface {
+	WithContext(ctx context.Context) CommentRepository
+
+	// List 按发布时间顺序返回一页评论和评论总数，包含作者和提及的用户
+	List(todoID uint, offset, limit int) ([]models.Comment, int64, error)
+	// GetById 返回待办事项下的评论，包含作者和提及的用户；不存在时返回 gorm.ErrRecordNotFound
+	GetById(todoID, id uint) (*models.Comment, error)
+	// Create 保存评论和提及的用户
+	Create(comment *models.Comment, mentions []uint) error
+	// Update 修改评论内容并替换提及的用户，标记为已编辑
+	Update(comment *models.Comment, body string, mentions []uint) error
+	Delete(comment *models.Comment) error
+	// MentionableUsers 返回 usernames 中有权查看该待办事项的用户，用户名不区分大小写
+	MentionableUsers(todo *models.Todo, usernames []string) ([]models.User, error)
+}
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+func (r *commentRepository) WithContext(ctx context.Context) CommentRepository {
+	return &commentRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *commentRepository) List(todoID uint, offset, limit int) ([]models.Comment, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Comment{}).Where("todo_id = ?", todoID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var comments []models.Comment
+	err := r.db.Preload("Author").Preload("Mentions.User").Where("todo_id = ?", todoID).
+		Order("created_at, id").Offset(offset).Limit(limit).Find(&comments).Error
+	return comments, total, err
+}
+
+func (r *commentRepository) GetById(todoID, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Preload("Author").Preload("Mentions.User").Where("todo_id = ?", todoID).First(&comment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func newMentions(commentID uint, uids []uint) []models.CommentMention {
+	mentions := make([]models.CommentMention, len(uids))
+	for i, uid := range uids {
+		mentions[i] = models.CommentMention{CommentId: commentID, UserId: uid}
+	}
+	return mentions
+}
+
+func (r *commentRepository) Create(comment *models.Comment, mentions []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Author", "Mentions").Create(comment).Error; err != nil {
+			return err
+		}
+		if len(mentions) == 0 {
+			return nil
+		}
+		comment.Mentions = newMentions(comment.ID, mentions)
+		return tx.Omit("User").Create(&comment.Mentions).Error
+	})
+}
+
+func (r *commentRepository) Update(comment *models.Comment, body string, mentions []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(comment).Omit("Author", "Mentions").
+			Updates(map[string]any{"body": body, "edited": true}).Error
+		if err != nil {
+			return err
+		}
+		comment.Body, comment.Edited = body, true
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		comment.Mentions = newMentions(comment.ID, mentions)
+		if len(mentions) == 0 {
+			return nil
+		}
+		return tx.Omit("User").Create(&comment.Mentions).Error
+	})
+}
+
+func (r *commentRepository) Delete(comment *models.Comment) error {
+	return r.db.Delete(comment).Error
+}
+
+func (r *commentRepository) MentionableUsers(todo *models.Todo, usernames []string) ([]models.User, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+	lower := make([]string, len(usernames))
+	for i, name := range usernames {
+		lower[i] = strings.ToLower(name)
+	}
+	query := r.db.Where("LOWER(username) IN ?", lower)
+	if todo.ProjectId == nil {
+		query = query.Where("id = ?", todo.UserId)
+	} else {
+		members := r.db.Session(&gorm.Session{NewDB: true}).Model(&models.ProjectMember{}).Select("user_id").
+			Where("project_id = ? AND status = ?", *todo.ProjectId, models.MemberAccepted)
+		query = query.Where("id IN (?)", members)
+	}
+	var users []models.User
+	err := query.Find(&users).Error
+	return users, err
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
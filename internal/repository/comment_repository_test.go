@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"testing"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentRepository(t *testing.T) {
+	comments := NewCommentRepository(db)
+	owner, editor := newTestUser(t, "comment-owner"), newTestUser(t, "comment-editor")
+	newTestUser(t, "comment-outsider")
+	projectID := newTestProject(t, owner, map[uint]string{editor: models.RoleEditor})
+	todo := &models.Todo{Title: "discussed", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, todo))
+
+	t.Run("list pages in posting order with the total", func(t *testing.T) {
+		for _, body := range []string{"first", "second", "third"} {
+			require.NoError(t, comments.Create(&models.Comment{TodoId: todo.ID, UserId: owner, Body: body}, nil))
+		}
+		page, total, err := comments.List(todo.ID, 0, 2)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, total)
+		require.Len(t, page, 2)
+		assert.Equal(t, "first", page[0].Body)
+		assert.Equal(t, "second", page[1].Body)
+		require.NotNil(t, page[0].Author)
+		assert.Equal(t, "comment-owner", page[0].Author.Username)
+
+		page, total, err = comments.List(todo.ID, 2, 2)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, total)
+		require.Len(t, page, 1)
+		assert.Equal(t, "third", page[0].Body)
+	})
+
+	t.Run("only users who can see the todo are mentionable", func(t *testing.T) {
+		pending := newTestUser(t, "comment-pending")
+		require.NoError(t, NewProjectRepository(db).Invite(&models.ProjectMember{ProjectId: projectID,
+			UserId: pending, Role: models.RoleEditor, InvitedBy: owner}))
+
+		users, err := comments.MentionableUsers(todo,
+			[]string{"Comment-Editor", "comment-outsider", "comment-pending", "nobody"})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, editor, users[0].ID)
+
+		personal := &models.Todo{Title: "personal", UserId: owner}
+		require.NoError(t, repo.Create(owner, personal))
+		users, err = comments.MentionableUsers(personal, []string{"comment-owner", "comment-editor"})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, owner, users[0].ID)
+	})
+
+	t.Run("update marks the comment edited and replaces mentions", func(t *testing.T) {
+		comment := &models.Comment{TodoId: todo.ID, UserId: owner, Body: "@comment-editor please look"}
+		require.NoError(t, comments.Create(comment, []uint{editor}))
+		assert.False(t, comment.Edited)
+
+		require.NoError(t, comments.Update(comment, "@comment-owner never mind", []uint{owner}))
+		saved, err := comments.GetById(todo.ID, comment.ID)
+		require.NoError(t, err)
+		assert.True(t, saved.Edited)
+		assert.Equal(t, "@comment-owner never mind", saved.Body)
+		require.Len(t, saved.Mentions, 1)
+		assert.Equal(t, owner, saved.Mentions[0].UserId)
+
+		require.NoError(t, comments.Update(comment, "no mentions", nil))
+		saved, err = comments.GetById(todo.ID, comment.ID)
+		require.NoError(t, err)
+		assert.Empty(t, saved.Mentions)
+	})
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type IdentityRepository interface {
+	// GetUserByIdentity 查找与外部身份关联的本地用户
+	GetUserByIdentity(issuer, subject string) (*models.User, error)
+	// CreateUserWithIdentity 在同一事务中创建本地用户和身份关联
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func (r *identityRepository) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := r.db.First(&user, identity.UserId).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *identityRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserId = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken) error
+	// GetValid 查找未使用且未过期的令牌
+	GetValid(hash string) (*models.PasswordResetToken, error)
+	// Consume 将令牌标记为已使用，令牌已被使用时返回 gorm.ErrRecordNotFound
+	Consume(id uint) error
+	// RevokeAll 作废用户所有未使用的令牌
+	RevokeAll(uid uint) error
+	// ResetPassword 在一个事务中使用令牌、为令牌的用户设置新密码并作废该用户其他未使用的令牌，
+	// 任何一步失败时令牌仍然可以继续使用。令牌已被使用时返回 gorm.ErrRecordNotFound
+	ResetPassword(token *models.PasswordResetToken, password string) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *passwordResetRepository) GetValid(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *passwordResetRepository) Consume(id uint) error {
+	result := r.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *passwordResetRepository) RevokeAll(uid uint) error {
+	return revokeResetTokens(r.db, uid)
+}
+
+func (r *passwordResetRepository) ResetPassword(token *models.PasswordResetToken, password string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := (&passwordResetRepository{db: tx}).Consume(token.ID); err != nil {
+			return err
+		}
+		if err := updatePassword(tx, token.UserId, password); err != nil {
+			return err
+		}
+		return revokeResetTokens(tx, token.UserId)
+	})
+}
+
+func revokeResetTokens(db *gorm.DB, uid uint) error {
+	return db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", uid).
+		Update("used_at", time.Now()).Error
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func TestResetPassword(t *testing.T) {
+	resets := NewPasswordResetRepository(db)
+	user := &models.User{Username: "reset-user", Password: "secret1"}
+	require.NoError(t, repo.CreateUser(user))
+	token := &models.PasswordResetToken{UserId: user.ID, TokenHash: "reset-hash", ExpiresAt: time.Now().Add(time.Hour)}
+	other := &models.PasswordResetToken{UserId: user.ID, TokenHash: "other-hash", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, resets.Create(token))
+	require.NoError(t, resets.Create(other))
+
+	require.NoError(t, resets.ResetPassword(token, "new-secret"))
+	updated, err := repo.GetUserById(user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-secret")))
+	assert.Equal(t, user.TokenVersion+1, updated.TokenVersion)
+
+	// 令牌只能使用一次，其他未使用的令牌也已作废
+	assert.ErrorIs(t, resets.ResetPassword(token, "another-secret"), gorm.ErrRecordNotFound)
+	_, err = resets.GetValid("other-hash")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PersonalTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	GetByHash(hash string) (*models.PersonalAccessToken, error)
+	ListByUser(uid uint) ([]models.PersonalAccessToken, error)
+	// Revoke 撤销用户的令牌，令牌不存在或不属于该用户时返回 gorm.ErrRecordNotFound
+	Revoke(uid, id uint) error
+	// Touch 记录令牌最近一次使用的时间和IP
+	Touch(id uint, at time.Time, ip string) error
+}
+
+type personalTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *personalTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *personalTokenRepository) GetByHash(hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *personalTokenRepository) ListByUser(uid uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := r.db.Where("user_id = ?", uid).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *personalTokenRepository) Revoke(uid, id uint) error {
+	result := r.db.Where("user_id = ?", uid).Delete(&models.PersonalAccessToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *personalTokenRepository) Touch(id uint, at time.Time, ip string) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("id = ?", id).
+		UpdateColumns(map[string]any{"last_used_at": at, "last_used_ip": ip}).Error
+}
+
+func NewPersonalTokenRepository(db *gorm.DB) PersonalTokenRepository {
+	return &personalTokenRepository{db: db}
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLastOwner 项目至少需要保留一个所有者
+var ErrLastOwner = errors.New("project must keep at least one owner")
+
+type ProjectRepository interface {
+	WithContext(ctx context.Context) ProjectRepository
+
+	// Create 创建项目，并把创建者加入为所有者
+	Create(project *models.Project) error
+	GetById(id uint) (*models.Project, error)
+	// ListByUser 返回用户已加入的项目（成员记录，包含项目信息）
+	ListByUser(uid uint) ([]models.ProjectMember, error)
+
+	// GetMember 返回用户在项目中的成员记录，不论邀请状态；不存在时返回 gorm.ErrRecordNotFound
+	GetMember(projectID, uid uint) (*models.ProjectMember, error)
+	// ListMembers 返回项目的成员和未处理的邀请，包含用户信息
+	ListMembers(projectID uint) ([]models.ProjectMember, error)
+	// Invite 创建邀请。用户之前拒绝过邀请时重新发出邀请
+	Invite(member *models.ProjectMember) error
+	// UpdateRole 修改成员的角色，不能把最后一个所有者降级
+	UpdateRole(projectID, uid uint, role string) error
+	// RemoveMember 移除成员或撤回邀请，不能移除最后一个所有者。
+	// 成员被移除后不再能查看项目，同时取消项目中指派给他的待办事项
+	RemoveMember(projectID, uid uint) error
+
+	// ListInvitations 返回用户待处理的邀请，包含项目信息
+	ListInvitations(uid uint) ([]models.ProjectMember, error)
+	// RespondInvitation 接受或拒绝发给 uid 的邀请，邀请不存在或已处理时返回 gorm.ErrRecordNotFound
+	RespondInvitation(uid, id uint, accept bool) (*models.ProjectMember, error)
+}
+
+type projectRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectRepository) WithContext(ctx context.Context) ProjectRepository {
+	return &projectRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *projectRepository) Create(project *models.Project) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ProjectMember{ProjectId: project.ID, UserId: project.UserId,
+			Role: models.RoleOwner, Status: models.MemberAccepted}).Error
+	})
+}
+
+func (r *projectRepository) GetById(id uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *projectRepository) ListByUser(uid uint) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.Joins("Project").
+		Where("project_members.user_id = ? AND project_members.status = ?", uid, models.MemberAccepted).
+		Order("project_members.created_at desc").Find(&members).Error
+	return members, err
+}
+
+func (r *projectRepository) GetMember(projectID, uid uint) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	if err := r.db.Where("project_id = ? AND user_id = ?", projectID, uid).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *projectRepository) ListMembers(projectID uint) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.Joins("User").Where("project_members.project_id = ? AND project_members.status <> ?",
+		projectID, models.MemberDeclined).Order("project_members.created_at").Find(&members).Error
+	return members, err
+}
+
+func (r *projectRepository) Invite(member *models.ProjectMember) error {
+	member.Status = models.MemberPending
+	// 已拒绝的邀请重新变为待处理，已经是成员或已有待处理的邀请时返回 gorm.ErrDuplicatedKey
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "user_id"}},
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "project_members.status", Value: models.MemberDeclined}}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "status", "invited_by", "updated_at"}),
+	}).Omit(clause.Associations).Create(member)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// lockOwners 在事务中锁定项目的所有者记录并返回所有者数量，避免并发操作移除了全部所有者
+func lockOwners(tx *gorm.DB, projectID uint) (int, error) {
+	var owners []models.ProjectMember
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND role = ? AND status = ?", projectID, models.RoleOwner, models.MemberAccepted).
+		Find(&owners).Error
+	return len(owners), err
+}
+
+func (r *projectRepository) UpdateRole(projectID, uid uint, role string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		owners, err := lockOwners(tx, projectID)
+		if err != nil {
+			return err
+		}
+		member, err := (&projectRepository{db: tx}).GetMember(projectID, uid)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner && role != models.RoleOwner && member.Status == models.MemberAccepted &&
+			owners <= 1 {
+			return ErrLastOwner
+		}
+		return tx.Model(member).Update("role", role).Error
+	})
+}
+
+func (r *projectRepository) RemoveMember(projectID, uid uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		owners, err := lockOwners(tx, projectID)
+		if err != nil {
+			return err
+		}
+		member, err := (&projectRepository{db: tx}).GetMember(projectID, uid)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner && member.Status == models.MemberAccepted && owners <= 1 {
+			return ErrLastOwner
+		}
+		if err := tx.Model(&models.Todo{}).Where("project_id = ? AND assignee_id = ?", projectID, uid).
+			Update("assignee_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(member).Error
+	})
+}
+
+func (r *projectRepository) ListInvitations(uid uint) ([]models.ProjectMember, error) {
+	var invitations []models.ProjectMember
+	err := r.db.Joins("Project").
+		Where("project_members.user_id = ? AND project_members.status = ?", uid, models.MemberPending).
+		Order("project_members.created_at desc").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *projectRepository) RespondInvitation(uid, id uint, accept bool) (*models.ProjectMember, error) {
+	status := models.MemberDeclined
+	if accept {
+		status = models.MemberAccepted
+	}
+	// 只更新仍处于待处理状态的邀请，重复提交不会改变已处理的结果
+	result := r.db.Model(&models.ProjectMember{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, uid, models.MemberPending).
+		Update("status", status)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var member models.ProjectMember
+	if err := r.db.Joins("Project").First(&member, id).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func NewProjectRepository(db *gorm.DB) ProjectRepository {
+	return &projectRepository{db: db}
+}
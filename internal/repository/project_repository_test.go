@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"testing"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestUser 创建测试用户，用户名在数据库中必须唯一
+func newTestUser(t *testing.T, username string) uint {
+	t.Helper()
+	user := &models.User{Username: username, Password: "secret1"}
+	require.NoError(t, repo.CreateUser(user))
+	return user.ID
+}
+
+// newTestProject 创建 owner 的项目，并按 roles 邀请其他用户、接受邀请
+func newTestProject(t *testing.T, owner uint, roles map[uint]string) uint {
+	t.Helper()
+	projects := NewProjectRepository(db)
+	project := &models.Project{Name: "shared", UserId: owner}
+	require.NoError(t, projects.Create(project))
+	for uid, role := range roles {
+		member := &models.ProjectMember{ProjectId: project.ID, UserId: uid, Role: role, InvitedBy: owner}
+		require.NoError(t, projects.Invite(member))
+		_, err := projects.RespondInvitation(uid, member.ID, true)
+		require.NoError(t, err)
+	}
+	return project.ID
+}
+
+func TestProjectAccess(t *testing.T) {
+	projects := NewProjectRepository(db)
+	owner, editor, viewer := newTestUser(t, "access-owner"), newTestUser(t, "access-editor"),
+		newTestUser(t, "access-viewer")
+	projectID := newTestProject(t, owner, map[uint]string{editor: models.RoleEditor, viewer: models.RoleViewer})
+	todo := &models.Todo{Title: "shared todo", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, todo))
+
+	t.Run("viewer can read but not modify", func(t *testing.T) {
+		_, err := repo.GetById(viewer, todo.ID)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, repo.Update(viewer, todo.ID), ErrForbidden)
+		assert.ErrorIs(t, repo.Delete(viewer, todo.ID), ErrForbidden)
+		assert.ErrorIs(t, repo.Assign(viewer, todo.ID, &viewer), ErrForbidden)
+		assert.ErrorIs(t, repo.Create(viewer, &models.Todo{Title: "x", UserId: viewer, ProjectId: &projectID}),
+			ErrForbidden)
+
+		unchanged, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.False(t, unchanged.Status)
+		assert.Nil(t, unchanged.AssigneeId)
+		assert.NoError(t, repo.Assign(editor, todo.ID, &viewer))
+	})
+
+	t.Run("pending and declined invitees cannot see project todos", func(t *testing.T) {
+		pending, declined := newTestUser(t, "access-pending"), newTestUser(t, "access-declined")
+		require.NoError(t, projects.Invite(&models.ProjectMember{ProjectId: projectID, UserId: pending,
+			Role: models.RoleEditor, InvitedBy: owner}))
+		invitation := &models.ProjectMember{ProjectId: projectID, UserId: declined, Role: models.RoleEditor,
+			InvitedBy: owner}
+		require.NoError(t, projects.Invite(invitation))
+		_, err := projects.RespondInvitation(declined, invitation.ID, false)
+		require.NoError(t, err)
+
+		for _, uid := range []uint{pending, declined} {
+			_, err := repo.GetById(uid, todo.ID)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			todos, err := repo.GetAll(uid, TodoFilter{})
+			assert.NoError(t, err)
+			assert.Empty(t, todos)
+			assert.ErrorIs(t, repo.Update(uid, todo.ID), gorm.ErrRecordNotFound)
+		}
+	})
+
+	t.Run("removed member loses access", func(t *testing.T) {
+		_, err := repo.GetById(editor, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, projects.RemoveMember(projectID, editor))
+
+		_, err = repo.GetById(editor, todo.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.Update(editor, todo.ID), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.Create(editor, &models.Todo{Title: "x", UserId: editor, ProjectId: &projectID}),
+			ErrForbidden)
+	})
+
+	t.Run("last owner cannot be demoted or removed", func(t *testing.T) {
+		assert.ErrorIs(t, projects.UpdateRole(projectID, owner, models.RoleEditor), ErrLastOwner)
+		assert.ErrorIs(t, projects.RemoveMember(projectID, owner), ErrLastOwner)
+
+		// 有另一个所有者之后可以降级
+		require.NoError(t, projects.UpdateRole(projectID, viewer, models.RoleOwner))
+		assert.NoError(t, projects.UpdateRole(projectID, owner, models.RoleEditor))
+		assert.ErrorIs(t, projects.UpdateRole(projectID, viewer, models.RoleViewer), ErrLastOwner)
+	})
+}
+
+func TestAssignee(t *testing.T) {
+	projects := NewProjectRepository(db)
+	owner, editor, outsider := newTestUser(t, "assign-owner"), newTestUser(t, "assign-editor"),
+		newTestUser(t, "assign-outsider")
+	projectID := newTestProject(t, owner, map[uint]string{editor: models.RoleEditor})
+	mine := &models.Todo{Title: "assigned to editor", UserId: owner, ProjectId: &projectID}
+	open := &models.Todo{Title: "unassigned", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, mine))
+	require.NoError(t, repo.Create(owner, open))
+
+	t.Run("only members can be assigned", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Assign(owner, mine.ID, &outsider), ErrInvalidAssignee)
+		assert.ErrorIs(t, repo.Create(owner, &models.Todo{Title: "x", UserId: owner, ProjectId: &projectID,
+			AssigneeId: &outsider}), ErrInvalidAssignee)
+		require.NoError(t, repo.Assign(owner, mine.ID, &editor))
+
+		personal := &models.Todo{Title: "personal", UserId: owner}
+		require.NoError(t, repo.Create(owner, personal))
+		assert.ErrorIs(t, repo.Assign(owner, personal.ID, &editor), ErrInvalidAssignee)
+		assert.NoError(t, repo.Assign(owner, personal.ID, &owner))
+	})
+
+	t.Run("filter by assignee", func(t *testing.T) {
+		todos, err := repo.GetAll(editor, TodoFilter{AssigneeId: &editor})
+		require.NoError(t, err)
+		require.Len(t, todos, 1)
+		assert.Equal(t, mine.ID, todos[0].ID)
+
+		todos, err = repo.GetAll(editor, TodoFilter{Unassigned: true})
+		require.NoError(t, err)
+		require.Len(t, todos, 1)
+		assert.Equal(t, open.ID, todos[0].ID)
+	})
+
+	t.Run("removing a member unassigns their todos", func(t *testing.T) {
+		require.NoError(t, projects.RemoveMember(projectID, editor))
+		todo, err := repo.GetById(owner, mine.ID)
+		require.NoError(t, err)
+		assert.Nil(t, todo.AssigneeId)
+		assert.ErrorIs(t, repo.Assign(owner, mine.ID, &editor), ErrInvalidAssignee)
+	})
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	// Replace 删除用户已有的恢复码并保存新的一组
+	Replace(uid uint, hashes []string) error
+	// Use 将一个未使用的恢复码标记为已使用，找不到时返回 gorm.ErrRecordNotFound
+	Use(uid uint, hash string) error
+	DeleteAll(uid uint) error
+	CountUnused(uid uint) (int64, error)
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func (r *recoveryCodeRepository) Replace(uid uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", uid).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(hashes))
+		for i, h := range hashes {
+			codes[i] = models.RecoveryCode{UserId: uid, CodeHash: h}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) Use(uid uint, hash string) error {
+	// 条件更新保证同一个恢复码只能被使用一次
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", uid, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *recoveryCodeRepository) DeleteAll(uid uint) error {
+	return r.db.Unscoped().Where("user_id = ?", uid).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r *recoveryCodeRepository) CountUnused(uid uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", uid).Count(&count).Error
+	return count, err
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
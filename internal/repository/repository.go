@@ -1,26 +1,161 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"time"
 	"todolist-api/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 type TodoRepository interface {
+	// WithContext 返回使用 ctx 执行查询的仓库，用于链路追踪和取消
+	WithContext(ctx context.Context) TodoRepository
+	// Primary 返回只从主库读取的仓库，用于写入之后立即读取的场景，避免读到副本上的旧数据
+	Primary() TodoRepository
+
 	// CreateUser  user method
 	CreateUser(user *models.User) error
 	GetUserByUsername(username string) (*models.User, error)
+	GetUserById(id uint) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	UpdatePassword(uid uint, password string) error
+	UpdateUser(user *models.User, fields map[string]any) error
+	AdvanceTOTPStep(uid uint, step int64) (bool, error)
+
+	// 待办事项的查询都按用户权限过滤：用户可以访问自己的个人待办事项和已加入的项目中的待办事项，
+	// 修改项目中的待办事项需要 editor 或 owner 角色。
+	// 无权查看时返回 gorm.ErrRecordNotFound，可以查看但无权修改时返回 ErrForbidden
 
-	Create(todo *models.Todo) error
-	GetAll(uid uint) ([]models.Todo, error)
-	GetById(id uint) (*models.Todo, error)
-	Update(id uint) error
-	Delete(id uint) error
+	// Create 创建待办事项，属于项目时要求 uid 是该项目的编辑者或所有者
+	Create(uid uint, todo *models.Todo) error
+	GetAll(uid uint, filter TodoFilter) ([]models.Todo, error)
+	GetById(uid, id uint) (*models.Todo, error)
+	// Update 切换完成状态。项目定义了工作流状态时移动到第一个 done 与新的完成状态相同的状态，
+	// 没有这样的状态时离开工作流。还有未完成的阻塞者时不能完成，返回 ErrBlocked
+	Update(uid, id uint) error
+	Delete(uid, id uint) error
+	// Assign 把待办事项指派给 assigneeID，为 nil 时取消指派。需要修改权限，
+	// 被指派的用户无权查看该待办事项时返回 ErrInvalidAssignee
+	Assign(uid, id uint, assigneeID *uint) error
+	// CheckWritable 检查用户能否修改待办事项
+	CheckWritable(uid, id uint) error
+	// Move 手动排序，把待办事项移动到同一列表中 after 之后、before 之前，至少要给出一个。
+	// 通常只修改被移动的待办事项，位置的间隔用尽时在同一个事务中重新分配整个列表的位置。
+	// before、after 不在同一个列表中或者顺序相反时返回 ErrInvalidMove
+	Move(uid, id uint, before, after *uint) error
+	// SetState 把项目中的待办事项移动到该项目的工作流状态 stateID，完成状态随之改变。
+	// 状态不属于待办事项所在的项目时返回 ErrInvalidState，移动到算作完成的状态时与 Update 一样检查阻塞者
+	SetState(uid, id, stateID uint) error
+	// StateTransitions 按时间顺序返回待办事项进入各个工作流状态的记录，包含状态信息（包括已删除的状态）
+	StateTransitions(id uint) ([]models.TodoStateTransition, error)
+	// AddBlocker 记录待办事项 id 要等 blockerID 完成之后才能完成，需要 id 的修改权限。
+	// blockerID 不在同一个列表中时返回 ErrInvalidBlocker，会形成环时返回 ErrDependencyCycle，已经存在时不做修改
+	AddBlocker(uid, id, blockerID uint) error
+	// RemoveBlocker 删除阻塞关系，需要 id 的修改权限，关系不存在时返回 gorm.ErrRecordNotFound
+	RemoveBlocker(uid, id, blockerID uint) error
+	// Blockers 返回阻塞待办事项 id 的待办事项，按手动排序的顺序排列
+	Blockers(id uint) ([]models.Todo, error)
+	// DependencyGraph 返回一个列表（projectID 为空时是 uid 的个人待办事项）中的待办事项和它们之间的阻塞关系，
+	// 待办事项按拓扑顺序排列，阻塞者在前，没有先后要求的按手动排序的顺序。无权查看项目时返回 gorm.ErrRecordNotFound
+	DependencyGraph(uid uint, projectID *uint) ([]models.Todo, []models.TodoDependency, error)
+	// GetIncludingDeleted 与 GetById 相同，但包括已删除、还没有彻底清除的待办事项
+	GetIncludingDeleted(uid, id uint) (*models.Todo, error)
+
+	// DeletedBefore 返回最多 limit 个在 before 之前删除的待办事项的 ID
+	DeletedBefore(before time.Time, limit int) ([]uint, error)
+	// Purge 彻底删除这些待办事项以及它们的评论和附件记录，跳过在此期间被恢复的，返回实际删除的 ID。
+	// 附件的文件需要调用方在返回之后删除
+	Purge(ids []uint) ([]uint, error)
 }
+
+// TodoFilter 列表查询的过滤条件，零值表示不过滤
+type TodoFilter struct {
+	// AssigneeId 只返回指派给该用户的待办事项
+	AssigneeId *uint
+	// Unassigned 只返回未指派的待办事项
+	Unassigned bool
+	// Sort 排序方式，为空时按创建时间倒序
+	Sort string
+}
+
+// SortManual 按手动排序的位置排列，同一个列表的待办事项排在一起，个人待办事项在前
+const SortManual = "manual"
+
+// manualOrder 手动排序的顺序，位置相同时与默认顺序一致
+const manualOrder = "todos.position, todos.created_at desc, todos.id desc"
+
+// order 返回列表的排序
+func (f TodoFilter) order() string {
+	if f.Sort == SortManual {
+		return "todos.project_id IS NOT NULL, todos.project_id, " + manualOrder
+	}
+	return "created_at desc"
+}
+
+func (f TodoFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.AssigneeId != nil {
+		db = db.Where("todos.assignee_id = ?", *f.AssigneeId)
+	}
+	if f.Unassigned {
+		db = db.Where("todos.assignee_id IS NULL")
+	}
+	return db
+}
+
+var (
+	// ErrForbidden 用户可以查看但没有权限修改
+	ErrForbidden = errors.New("permission denied")
+	// ErrInvalidAssignee 被指派的用户不存在或无权查看该待办事项
+	ErrInvalidAssignee = errors.New("assignee has no access to this todo")
+	// ErrInvalidMove 移动的参照待办事项不在同一个列表中或者顺序相反
+	ErrInvalidMove = errors.New("before and after must be other todos in the same list, in order")
+	// ErrInvalidBlocker 阻塞者不存在、是待办事项本身或者不在同一个列表中
+	ErrInvalidBlocker = errors.New("blocker must be another todo in the same list")
+	// ErrDependencyCycle 添加阻塞关系会形成环
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	// ErrBlocked 还有未完成的阻塞者，不能完成
+	ErrBlocked = errors.New("todo is blocked by open todos")
+)
+
+// accessibleTo 限定为用户可以访问的待办事项：自己的个人待办事项，以及已接受邀请的项目中的待办事项。
+// 给出 roles 时只包括用户在项目中具有其中某个角色的待办事项
+func accessibleTo(uid uint, roles ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		projects := db.Session(&gorm.Session{NewDB: true}).Model(&models.ProjectMember{}).Select("project_id").
+			Where("user_id = ? AND status = ?", uid, models.MemberAccepted)
+		if len(roles) > 0 {
+			projects = projects.Where("role IN ?", roles)
+		}
+		return db.Where("((todos.project_id IS NULL AND todos.user_id = ?) OR todos.project_id IN (?))", uid, projects)
+	}
+}
+
+// listOf 限定为与 todo 在同一个列表中的待办事项：同一个项目，或者同一个用户的个人待办事项
+func listOf(todo *models.Todo) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if todo.ProjectId != nil {
+			return db.Where("todos.project_id = ?", *todo.ProjectId)
+		}
+		return db.Where("todos.project_id IS NULL AND todos.user_id = ?", todo.UserId)
+	}
+}
+
 type todoRepository struct {
 	db *gorm.DB
 }
 
+func (t *todoRepository) WithContext(ctx context.Context) TodoRepository {
+	return &todoRepository{db: t.db.WithContext(ctx)}
+}
+
+func (t *todoRepository) Primary() TodoRepository {
+	return &todoRepository{db: t.db.Clauses(dbresolver.Write).Session(&gorm.Session{})}
+}
+
 func (t *todoRepository) CreateUser(user *models.User) error {
 	return t.db.Create(user).Error
 }
@@ -31,38 +166,612 @@ func (t *todoRepository) GetUserByUsername(username string) (*models.User, error
 	}
 	return &user, nil
 }
-func (t *todoRepository) Create(todo *models.Todo) error {
-	return t.db.Create(todo).Error
+func (t *todoRepository) GetUserById(id uint) (*models.User, error) {
+	var user models.User
+	if err := t.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (t *todoRepository) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := t.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdatePassword 更新密码并递增令牌版本，使之前签发的令牌全部失效
+func (t *todoRepository) UpdatePassword(uid uint, password string) error {
+	return updatePassword(t.db, uid, password)
+}
+
+// updatePassword 哈希并保存新密码，同时递增令牌版本
+func updatePassword(db *gorm.DB, uid uint, password string) error {
+	hashed, err := models.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	// 使用 UpdateColumns 跳过 BeforeSave，避免对已经哈希过的密码再次哈希
+	return db.Model(&models.User{}).Where("id = ?", uid).UpdateColumns(map[string]any{
+		"password":      hashed,
+		"token_version": gorm.Expr("token_version + 1"),
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+// UpdateUser 只更新 fields 中给出的列
+func (t *todoRepository) UpdateUser(user *models.User, fields map[string]any) error {
+	return t.db.Model(user).Updates(fields).Error
+}
+
+// AdvanceTOTPStep 记录最近一次使用的 TOTP 时间步，只有 step 大于已记录的值时才会更新，
+// 返回 false 表示该验证码已经被使用过（包括并发请求的情况）
+func (t *todoRepository) AdvanceTOTPStep(uid uint, step int64) (bool, error) {
+	result := t.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", uid, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+func (t *todoRepository) Create(uid uint, todo *models.Todo) error {
+	if todo.ProjectId != nil {
+		var count int64
+		err := t.db.Clauses(dbresolver.Write).Model(&models.ProjectMember{}).
+			Where("project_id = ? AND user_id = ? AND status = ? AND role IN ?",
+				*todo.ProjectId, uid, models.MemberAccepted, models.WriteRoles).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrForbidden
+		}
+	}
+	if todo.AssigneeId != nil {
+		if err := t.checkAssignee(todo, *todo.AssigneeId); err != nil {
+			return err
+		}
+	}
+	// 新建的待办事项排在列表的最前面，与默认的按创建时间倒序一致
+	var first *float64
+	err := t.db.Clauses(dbresolver.Write).Model(&models.Todo{}).Scopes(listOf(todo)).
+		Select("MIN(position)").Scan(&first).Error
+	if err != nil {
+		return err
+	}
+	todo.Position, _ = models.PositionBetween(nil, first)
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		// 项目定义了工作流状态时，新建的待办事项进入第一个未完成的状态
+		state, err := stateFor(tx, todo.ProjectId, todo.Status)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(todo).Error; err != nil || state == nil {
+			return err
+		}
+		return changeState(tx, todo, state, todo.Status, uid)
+	})
+}
+
+// checkAssignee 检查用户能否被指派到该待办事项：个人待办事项只能指派给创建者，
+// 项目中的待办事项可以指派给任何已加入项目的成员
+func (t *todoRepository) checkAssignee(todo *models.Todo, assigneeID uint) error {
+	if todo.ProjectId == nil {
+		if assigneeID != todo.UserId {
+			return ErrInvalidAssignee
+		}
+		return nil
+	}
+	var count int64
+	err := t.db.Clauses(dbresolver.Write).Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ? AND status = ?", *todo.ProjectId, assigneeID, models.MemberAccepted).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInvalidAssignee
+	}
+	return nil
 }
 
-func (t *todoRepository) GetAll(uid uint) ([]models.Todo, error) {
+func (t *todoRepository) GetAll(uid uint, filter TodoFilter) ([]models.Todo, error) {
 	var todos []models.Todo
-	err := t.db.Where("user_id = ?", uid).Order("created_at desc").Find(&todos).Error
-	return todos, err
+	err := t.db.Scopes(accessibleTo(uid), filter.scope).Order(filter.order()).Find(&todos).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := t.countComments(todos); err != nil {
+		return nil, err
+	}
+	return todos, t.markBlocked(todos)
+}
+
+func (t *todoRepository) GetById(uid, id uint) (*models.Todo, error) {
+	todos := make([]models.Todo, 1)
+	err := t.db.Scopes(accessibleTo(uid)).First(&todos[0], id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := t.countComments(todos); err != nil {
+		return nil, err
+	}
+	if err := t.markBlocked(todos); err != nil {
+		return nil, err
+	}
+	return &todos[0], nil
 }
 
-func (t *todoRepository) GetById(id uint) (*models.Todo, error) {
+func (t *todoRepository) GetIncludingDeleted(uid, id uint) (*models.Todo, error) {
 	var todo models.Todo
-	err := t.db.First(&todo, id).Error
+	if err := t.db.Unscoped().Scopes(accessibleTo(uid)).First(&todo, id).Error; err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// countComments 填充待办事项的评论数量
+func (t *todoRepository) countComments(todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	for i := range todos {
+		ids[i] = todos[i].ID
+	}
+	var counts []struct {
+		TodoId uint
+		Count  int64
+	}
+	err := t.db.Model(&models.Comment{}).Select("todo_id, COUNT(*) AS count").Where("todo_id IN ?", ids).
+		Group("todo_id").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	byTodo := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byTodo[c.TodoId] = c.Count
+	}
+	for i := range todos {
+		todos[i].CommentCount = byTodo[todos[i].ID]
+	}
+	return nil
+}
+
+// openBlockers 限定为阻塞关系中还没有完成、也没有被删除的阻塞者
+func openBlockers(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN todos AS blockers ON blockers.id = todo_dependencies.blocker_id").
+		Where("blockers.status = ? AND blockers.deleted_at IS NULL", false)
+}
+
+// markBlocked 填充待办事项是否被阻塞
+func (t *todoRepository) markBlocked(todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	for i := range todos {
+		ids[i] = todos[i].ID
+	}
+	var blocked []uint
+	err := t.db.Model(&models.TodoDependency{}).Scopes(openBlockers).
+		Where("todo_dependencies.todo_id IN ?", ids).Distinct().Pluck("todo_dependencies.todo_id", &blocked).Error
+	if err != nil {
+		return err
+	}
+	byTodo := make(map[uint]bool, len(blocked))
+	for _, id := range blocked {
+		byTodo[id] = true
+	}
+	for i := range todos {
+		todos[i].Blocked = byTodo[todos[i].ID]
+	}
+	return nil
+}
+
+// checkUnblocked 待办事项还有未完成的阻塞者时返回 ErrBlocked
+func checkUnblocked(tx *gorm.DB, id uint) error {
+	var count int64
+	err := tx.Model(&models.TodoDependency{}).Scopes(openBlockers).Where("todo_dependencies.todo_id = ?", id).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// writable 从主库读取用户有权修改的待办事项
+func (t *todoRepository) writable(uid, id uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := t.db.Clauses(dbresolver.Write).Scopes(accessibleTo(uid, models.WriteRoles...)).First(&todo, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 区分不存在（或无权查看）和只能查看两种情况
+		if _, readErr := t.Primary().GetById(uid, id); readErr == nil {
+			return nil, ErrForbidden
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &todo, nil
 }
 
-func (t *todoRepository) Update(id uint) error {
+// lockTodo 在事务中重新读取待办事项并加行锁。权限检查在事务之外读取，
+// 并发的切换或移动可能已经改变了完成状态和工作流状态，必须在锁住之后再计算新的状态
+func lockTodo(tx *gorm.DB, id uint) (*models.Todo, error) {
 	var todo models.Todo
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&todo, id).Error; err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (t *todoRepository) CheckWritable(uid, id uint) error {
+	_, err := t.writable(uid, id)
+	return err
+}
+
+func (t *todoRepository) Update(uid, id uint) error {
+	if _, err := t.writable(uid, id); err != nil {
+		return err
+	}
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		todo, err := lockTodo(tx, id)
+		if err != nil {
+			return err
+		}
+		if !todo.Status {
+			if err := checkUnblocked(tx, todo.ID); err != nil {
+				return err
+			}
+		}
+		state, err := stateFor(tx, todo.ProjectId, !todo.Status)
+		if err != nil {
+			return err
+		}
+		return changeState(tx, todo, state, !todo.Status, uid)
+	})
+}
+
+func (t *todoRepository) Delete(uid, id uint) error {
+	todo, err := t.writable(uid, id)
+	if err != nil {
+		return err
+	}
+	return t.db.Delete(todo).Error
+}
+
+func (t *todoRepository) Assign(uid, id uint, assigneeID *uint) error {
+	todo, err := t.writable(uid, id)
+	if err != nil {
+		return err
+	}
+	if assigneeID != nil {
+		if err := t.checkAssignee(todo, *assigneeID); err != nil {
+			return err
+		}
+	}
+	return t.db.Model(todo).Update("assignee_id", assigneeID).Error
+}
 
-	err := t.db.First(&todo, id).Error
+func (t *todoRepository) SetState(uid, id, stateID uint) error {
+	todo, err := t.writable(uid, id)
 	if err != nil {
 		return err
 	}
-	todo.Status = !todo.Status
-	return t.db.Save(todo).Error
+	if todo.ProjectId == nil {
+		return ErrInvalidState
+	}
+	var state models.WorkflowState
+	err = t.db.Clauses(dbresolver.Write).Where("project_id = ?", *todo.ProjectId).First(&state, stateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidState
+	}
+	if err != nil {
+		return err
+	}
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		todo, err := lockTodo(tx, id)
+		if err != nil {
+			return err
+		}
+		if state.Done && !todo.Status {
+			if err := checkUnblocked(tx, todo.ID); err != nil {
+				return err
+			}
+		}
+		return changeState(tx, todo, &state.ID, state.Done, uid)
+	})
 }
 
-func (t *todoRepository) Delete(id uint) error {
-	return t.db.Delete(&models.Todo{}, id).Error
+func (t *todoRepository) StateTransitions(id uint) ([]models.TodoStateTransition, error) {
+	var transitions []models.TodoStateTransition
+	// 包括已删除的状态，保留在其中停留的时间
+	err := t.db.Preload("State", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Where("todo_id = ?", id).
+		Order("entered_at, id").Find(&transitions).Error
+	return transitions, err
+}
+
+func (t *todoRepository) AddBlocker(uid, id, blockerID uint) error {
+	todo, err := t.writable(uid, id)
+	if err != nil {
+		return err
+	}
+	if blockerID == todo.ID {
+		return ErrInvalidBlocker
+	}
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockList(tx, todo); err != nil {
+			return err
+		}
+		var count int64
+		err := tx.Model(&models.Todo{}).Scopes(listOf(todo)).Where("id = ?", blockerID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrInvalidBlocker
+		}
+		// 包括已删除的待办事项之间的关系，避免恢复之后形成环
+		list := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Todo{}).Select("id").
+			Scopes(listOf(todo))
+		var deps []models.TodoDependency
+		if err := tx.Where("todo_id IN (?)", list).Find(&deps).Error; err != nil {
+			return err
+		}
+		for _, d := range deps {
+			if d.TodoId == todo.ID && d.BlockerId == blockerID {
+				return nil
+			}
+		}
+		if models.CreatesCycle(deps, todo.ID, blockerID) {
+			return ErrDependencyCycle
+		}
+		return tx.Create(&models.TodoDependency{TodoId: todo.ID, BlockerId: blockerID, UserId: uid}).Error
+	})
+}
+
+// lockList 锁定待办事项所在的列表（项目或者个人待办事项的用户），使同一个列表中的阻塞关系依次修改，
+// 并发添加的关系不会绕过环的检查
+func lockList(tx *gorm.DB, todo *models.Todo) error {
+	locking := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if todo.ProjectId != nil {
+		return locking.Select("id").First(&models.Project{}, *todo.ProjectId).Error
+	}
+	return locking.Select("id").First(&models.User{}, todo.UserId).Error
+}
+
+func (t *todoRepository) RemoveBlocker(uid, id, blockerID uint) error {
+	if _, err := t.writable(uid, id); err != nil {
+		return err
+	}
+	result := t.db.Where("todo_id = ? AND blocker_id = ?", id, blockerID).Delete(&models.TodoDependency{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (t *todoRepository) Blockers(id uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	blockers := t.db.Session(&gorm.Session{NewDB: true}).Model(&models.TodoDependency{}).Select("blocker_id").
+		Where("todo_id = ?", id)
+	if err := t.db.Where("id IN (?)", blockers).Order(manualOrder).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	if err := t.countComments(todos); err != nil {
+		return nil, err
+	}
+	return todos, t.markBlocked(todos)
+}
+
+func (t *todoRepository) DependencyGraph(uid uint, projectID *uint) ([]models.Todo, []models.TodoDependency, error) {
+	list := listOf(&models.Todo{UserId: uid, ProjectId: projectID})
+	var todos []models.Todo
+	err := t.db.Scopes(accessibleTo(uid), list).Order(manualOrder).Find(&todos).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if projectID != nil && len(todos) == 0 {
+		// 区分空项目和无权查看的项目
+		var count int64
+		err := t.db.Model(&models.ProjectMember{}).
+			Where("project_id = ? AND user_id = ? AND status = ?", *projectID, uid, models.MemberAccepted).
+			Count(&count).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		if count == 0 {
+			return nil, nil, gorm.ErrRecordNotFound
+		}
+	}
+	ids := make([]uint, len(todos))
+	byID := make(map[uint]models.Todo, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+		byID[todo.ID] = todo
+	}
+	var deps []models.TodoDependency
+	if len(ids) > 0 {
+		err := t.db.Where("todo_id IN ? AND blocker_id IN ?", ids, ids).Order("id").Find(&deps).Error
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	order, ok := models.TopologicalOrder(ids, deps)
+	if !ok {
+		return nil, nil, ErrDependencyCycle
+	}
+	for i, id := range order {
+		todos[i] = byID[id]
+	}
+	if err := t.countComments(todos); err != nil {
+		return nil, nil, err
+	}
+	return todos, deps, t.markBlocked(todos)
+}
+
+func (t *todoRepository) Move(uid, id uint, before, after *uint) error {
+	if before == nil && after == nil {
+		return ErrInvalidMove
+	}
+	todo, err := t.writable(uid, id)
+	if err != nil {
+		return err
+	}
+	list := listOf(todo)
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		// 最多重新分配一次，之后间隔一定足够
+		for rebalanced := false; ; rebalanced = true {
+			position, ok, err := positionFor(tx, list, todo.ID, before, after)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return tx.Model(todo).Update("position", position).Error
+			}
+			if rebalanced {
+				return ErrInvalidMove
+			}
+			if err := rebalance(tx, list); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// positionFor 计算待办事项 id 移动到 after 之后、before 之前的位置。
+// 相邻的位置相同或者间隔用尽时返回 false，需要重新分配位置后再计算
+func positionFor(tx *gorm.DB, list func(*gorm.DB) *gorm.DB, id uint, before, after *uint) (float64, bool, error) {
+	anchor := func(anchorID uint) (*models.Todo, error) {
+		if anchorID == id {
+			return nil, ErrInvalidMove
+		}
+		var todo models.Todo
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(list).First(&todo, anchorID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMove
+		}
+		return &todo, err
+	}
+	// neighbour 返回列表中除 id 和 anchorID 之外、满足条件的第一个位置
+	neighbour := func(anchorID uint, cond string, value float64, order string) (*float64, error) {
+		var positions []float64
+		err := tx.Model(&models.Todo{}).Scopes(list).Where("id NOT IN ?", []uint{id, anchorID}).
+			Where(cond, value).Order(order).Limit(1).Pluck("position", &positions).Error
+		if err != nil || len(positions) == 0 {
+			return nil, err
+		}
+		return &positions[0], nil
+	}
+
+	var prev, next *float64
+	if after != nil {
+		todo, err := anchor(*after)
+		if err != nil {
+			return 0, false, err
+		}
+		prev = &todo.Position
+	}
+	if before != nil {
+		todo, err := anchor(*before)
+		if err != nil {
+			return 0, false, err
+		}
+		next = &todo.Position
+	}
+	var err error
+	switch {
+	case prev != nil && next != nil:
+		if *prev > *next {
+			return 0, false, ErrInvalidMove
+		}
+	case prev != nil:
+		// 包括位置相同的，它们与 after 的先后无法只靠位置区分
+		next, err = neighbour(*after, "position >= ?", *prev, "position")
+	default:
+		prev, err = neighbour(*before, "position <= ?", *next, "position desc")
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if prev != nil && next != nil && *prev == *next {
+		return 0, false, nil
+	}
+	position, ok := models.PositionBetween(prev, next)
+	return position, ok, nil
+}
+
+// rebalance 按当前的顺序重新分配列表中所有待办事项的位置
+func rebalance(tx *gorm.DB, list func(*gorm.DB) *gorm.DB) error {
+	var ids []uint
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Todo{}).Scopes(list).Order(manualOrder).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		err := tx.Model(&models.Todo{}).Where("id = ?", id).
+			UpdateColumn("position", float64(i+1)*models.PositionStep).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *todoRepository) DeletedBefore(before time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := t.db.Clauses(dbresolver.Write).Unscoped().Model(&models.Todo{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Order("deleted_at").Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (t *todoRepository) Purge(ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var deleted []uint
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		// 只清除仍处于已删除状态的待办事项，跳过在此期间被恢复的
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().Model(&models.Todo{}).
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).Pluck("id", &deleted).Error
+		if err != nil || len(deleted) == 0 {
+			return err
+		}
+		ids := deleted
+		comments := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Comment{}).Select("id").
+			Where("todo_id IN ?", ids)
+		if err := tx.Where("comment_id IN (?)", comments).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("todo_id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ?", ids).Delete(&models.TodoStateTransition{}).Error; err != nil {
+			return err
+		}
+		err = tx.Where("todo_id IN ? OR blocker_id IN ?", ids, ids).Delete(&models.TodoDependency{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Todo{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
 }
 
 func NewTodoRepository(db *gorm.DB) TodoRepository {
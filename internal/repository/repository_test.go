@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"todolist-api/internal/database"
 	"todolist-api/internal/models"
 	"todolist-api/pkg/config"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -29,11 +31,19 @@ func setup() {
 		log.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	// 每次测试前都清空并重新迁移表，保证测试环境干净
-	db.Migrator().DropTable(&models.Todo{})
-	db.AutoMigrate(&models.Todo{})
+	// 每次测试前都清空并重新迁移所有表，保证测试环境干净
+	if err := db.Migrator().DropTable(database.Models...); err != nil {
+		log.Fatalf("Failed to drop tables: %v", err)
+	}
+	if err := db.AutoMigrate(database.Models...); err != nil {
+		log.Fatalf("Failed to migrate test database: %v", err)
+	}
 
 	repo = NewTodoRepository(db)
+	// 待办事项的 user_id 有外键约束，先创建测试中使用的用户 1
+	if err := repo.CreateUser(&models.User{Username: "tester", Password: "secret1"}); err != nil {
+		log.Fatalf("Failed to create test user: %v", err)
+	}
 }
 func TestMain(m *testing.M) {
 	setup()
@@ -42,20 +52,20 @@ func TestMain(m *testing.M) {
 func TestTodoRepository(t *testing.T) {
 	t.Run("Create and Get Todo", func(t *testing.T) {
 		// 1. Create
-		newTodo := &models.Todo{Title: "Test Todo", Status: false}
-		err := repo.Create(newTodo)
+		newTodo := &models.Todo{Title: "Test Todo", Status: false, UserId: 1}
+		err := repo.Create(1, newTodo)
 		assert.NoError(t, err)
 		assert.NotZero(t, newTodo.ID)
 
 		// 2. Get By ID
-		foundTodo, err := repo.GetById(newTodo.ID)
+		foundTodo, err := repo.GetById(1, newTodo.ID)
 		assert.NoError(t, err)
 		assert.NotNil(t, foundTodo)
 		assert.Equal(t, "Test Todo", foundTodo.Title)
 		assert.Equal(t, false, foundTodo.Status)
 
 		// 3. Get All
-		todos, err := repo.GetAll()
+		todos, err := repo.GetAll(1, TodoFilter{})
 		assert.NoError(t, err)
 		assert.Len(t, todos, 1)
 		assert.Equal(t, "Test Todo", todos[0].Title)
@@ -63,15 +73,15 @@ func TestTodoRepository(t *testing.T) {
 
 	t.Run("Update Todo", func(t *testing.T) {
 		// 先创建一个
-		todo := &models.Todo{Title: "Todo to be updated", Status: false}
-		repo.Create(todo)
+		todo := &models.Todo{Title: "Todo to be updated", Status: false, UserId: 1}
+		repo.Create(1, todo)
 
 		// 更新它
-		err := repo.Update(todo.ID)
+		err := repo.Update(1, todo.ID)
 		assert.NoError(t, err)
 
 		// 再次获取并验证
-		updatedTodo, err := repo.GetById(todo.ID)
+		updatedTodo, err := repo.GetById(1, todo.ID)
 		assert.NoError(t, err)
 		//assert.Equal(t, "Updated Title", updatedTodo.Title)
 		assert.Equal(t, true, updatedTodo.Status)
@@ -79,16 +89,108 @@ func TestTodoRepository(t *testing.T) {
 
 	t.Run("Delete Todo", func(t *testing.T) {
 		// 先创建一个
-		todo := &models.Todo{Title: "Todo to be deleted", Status: false}
-		repo.Create(todo)
+		todo := &models.Todo{Title: "Todo to be deleted", Status: false, UserId: 1}
+		repo.Create(1, todo)
 
 		// 删除它
-		err := repo.Delete(todo.ID)
+		err := repo.Delete(1, todo.ID)
 		assert.NoError(t, err)
 
 		// 尝试获取，应该会失败
-		_, err = repo.GetById(todo.ID)
+		_, err = repo.GetById(1, todo.ID)
 		assert.Error(t, err)
 		assert.Equal(t, gorm.ErrRecordNotFound, err)
 	})
 }
+
+func TestPurge(t *testing.T) {
+	purged := &models.Todo{Title: "purged", UserId: 1}
+	restored := &models.Todo{Title: "restored", UserId: 1}
+	for _, todo := range []*models.Todo{purged, restored} {
+		assert.NoError(t, repo.Create(1, todo))
+		assert.NoError(t, repo.Delete(1, todo.ID))
+	}
+	// 在列出之后、清除之前恢复
+	assert.NoError(t, db.Unscoped().Model(restored).Update("deleted_at", nil).Error)
+
+	ids, err := repo.Purge([]uint{purged.ID, restored.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{purged.ID}, ids)
+	_, err = repo.GetIncludingDeleted(1, purged.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.GetById(1, restored.ID)
+	assert.NoError(t, err)
+}
+
+func TestMove(t *testing.T) {
+	owner := newTestUser(t, "move-owner")
+	// titles 按 sort=manual 的顺序返回 uid 能看到的待办事项标题
+	titles := func(t *testing.T) []string {
+		t.Helper()
+		todos, err := repo.GetAll(owner, TodoFilter{Sort: SortManual})
+		require.NoError(t, err)
+		names := make([]string, len(todos))
+		for i, todo := range todos {
+			names[i] = todo.Title
+		}
+		return names
+	}
+	ids := map[string]uint{}
+	for _, title := range []string{"a", "b", "c"} {
+		todo := &models.Todo{Title: title, UserId: owner}
+		require.NoError(t, repo.Create(owner, todo))
+		ids[title] = todo.ID
+	}
+	id := func(title string) *uint {
+		v := ids[title]
+		return &v
+	}
+	// 新建的排在最前面
+	require.Equal(t, []string{"c", "b", "a"}, titles(t))
+
+	t.Run("to the front, the back and between two todos", func(t *testing.T) {
+		require.NoError(t, repo.Move(owner, ids["a"], id("c"), nil))
+		assert.Equal(t, []string{"a", "c", "b"}, titles(t))
+		require.NoError(t, repo.Move(owner, ids["a"], nil, id("b")))
+		assert.Equal(t, []string{"c", "b", "a"}, titles(t))
+		require.NoError(t, repo.Move(owner, ids["c"], id("a"), id("b")))
+		assert.Equal(t, []string{"b", "c", "a"}, titles(t))
+	})
+
+	t.Run("equal positions are rebalanced", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.Todo{}).Where("user_id = ?", owner).Update("position", 0).Error)
+		// 位置相同时按创建时间倒序
+		require.Equal(t, []string{"c", "b", "a"}, titles(t))
+		require.NoError(t, repo.Move(owner, ids["a"], nil, id("c")))
+		assert.Equal(t, []string{"c", "a", "b"}, titles(t))
+
+		var positions []float64
+		require.NoError(t, db.Model(&models.Todo{}).Where("user_id = ?", owner).Order("position").
+			Pluck("position", &positions).Error)
+		assert.Len(t, positions, 3)
+		assert.Less(t, positions[0], positions[1])
+		assert.Less(t, positions[1], positions[2])
+	})
+
+	t.Run("invalid anchors are rejected", func(t *testing.T) {
+		other := &models.Todo{Title: "other list", UserId: 1}
+		require.NoError(t, repo.Create(1, other))
+
+		assert.ErrorIs(t, repo.Move(owner, ids["a"], nil, nil), ErrInvalidMove)
+		assert.ErrorIs(t, repo.Move(owner, ids["a"], &other.ID, nil), ErrInvalidMove, "another list")
+		assert.ErrorIs(t, repo.Move(owner, ids["a"], id("a"), nil), ErrInvalidMove, "the todo itself")
+		// 当前顺序是 c、a、b，before 在 after 前面
+		assert.ErrorIs(t, repo.Move(owner, ids["a"], id("c"), id("b")), ErrInvalidMove, "reversed anchors")
+		assert.Equal(t, []string{"c", "a", "b"}, titles(t), "rejected moves change nothing")
+	})
+
+	t.Run("manual sort lists personal todos before project todos", func(t *testing.T) {
+		projectID := newTestProject(t, owner, nil)
+		first := &models.Todo{Title: "p1", UserId: owner, ProjectId: &projectID}
+		second := &models.Todo{Title: "p2", UserId: owner, ProjectId: &projectID}
+		require.NoError(t, repo.Create(owner, first))
+		require.NoError(t, repo.Create(owner, second))
+		require.NoError(t, repo.Move(owner, first.ID, &second.ID, nil))
+		assert.Equal(t, []string{"c", "a", "b", "p1", "p2"}, titles(t))
+	})
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidUndoToken 撤销令牌不存在、已使用、已过期或不属于当前用户
+var ErrInvalidUndoToken = errors.New("undo token is invalid or expired")
+
+type UndoRepository interface {
+	WithContext(ctx context.Context) UndoRepository
+
+	// Create 保存令牌，同时删除该用户已过期的令牌
+	Create(token *models.UndoToken) error
+	// Apply 在一个事务中把令牌标记为已使用，并把待办事项恢复为操作之前的状态（完成状态和工作流状态，撤销删除时还有删除状态），
+	// 返回恢复之前的待办事项。令牌无效时返回 ErrInvalidUndoToken；
+	// uid 已经无权修改其中某个待办事项时返回 gorm.ErrRecordNotFound 或 ErrForbidden，
+	// 要恢复为已完成的待办事项还有未完成的阻塞者时返回 ErrBlocked，此时不做任何修改
+	Apply(uid uint, tokenHash string) ([]models.Todo, error)
+}
+
+type undoRepository struct {
+	db *gorm.DB
+}
+
+func (r *undoRepository) WithContext(ctx context.Context) UndoRepository {
+	return &undoRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *undoRepository) Create(token *models.UndoToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND expires_at <= ?", token.UserId, time.Now()).
+			Delete(&models.UndoToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *undoRepository) Apply(uid uint, tokenHash string) ([]models.Todo, error) {
+	var before []models.Todo
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var token models.UndoToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", tokenHash, uid, time.Now()).
+			First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidUndoToken
+		}
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&token).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidUndoToken
+		}
+		for _, snapshot := range token.Snapshot {
+			// 已删除的待办事项也要能找到，但仍然要求 uid 现在有修改权限
+			var todo models.Todo
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().
+				Scopes(accessibleTo(uid, models.WriteRoles...)).First(&todo, snapshot.ID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				var count int64
+				err := tx.Unscoped().Model(&models.Todo{}).Scopes(accessibleTo(uid)).Where("id = ?", snapshot.ID).
+					Count(&count).Error
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					return ErrForbidden
+				}
+			}
+			if err != nil {
+				return err
+			}
+			before = append(before, todo)
+			state, status, err := resolveState(tx, todo.ProjectId, snapshot.StateId, snapshot.Status)
+			if err != nil {
+				return err
+			}
+			if status && !todo.Status {
+				if err := checkUnblocked(tx, todo.ID); err != nil {
+					return err
+				}
+			}
+			if err := changeState(tx, &todo, state, status, uid); err != nil {
+				return err
+			}
+			// 只有撤销删除时才恢复删除状态，撤销完成状态时待办事项之后被删除的话仍然保持删除
+			if token.Action != models.UndoDelete {
+				continue
+			}
+			if err := tx.Unscoped().Model(&todo).Update("deleted_at", snapshot.DeletedAt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+func NewUndoRepository(db *gorm.DB) UndoRepository {
+	return &undoRepository{db: db}
+}
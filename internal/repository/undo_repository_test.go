@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUndoRepository(t *testing.T) {
+	undo := NewUndoRepository(db)
+	owner, other := newTestUser(t, "undo-owner"), newTestUser(t, "undo-other")
+	// issue 保存 uid 在 before 状态上签发的令牌
+	issue := func(t *testing.T, uid uint, hash, action string, before *models.Todo, ttl time.Duration) {
+		t.Helper()
+		require.NoError(t, undo.Create(&models.UndoToken{UserId: uid, TokenHash: hash, Action: action,
+			Snapshot: []models.Todo{*before}, ExpiresAt: time.Now().Add(ttl)}))
+	}
+
+	t.Run("delete then undo restores the todo", func(t *testing.T) {
+		todo := &models.Todo{Title: "undo delete", UserId: owner}
+		require.NoError(t, repo.Create(owner, todo))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(owner, todo.ID))
+		issue(t, owner, "undo-delete", models.UndoDelete, before, time.Minute)
+
+		restored, err := undo.Apply(owner, "undo-delete")
+		require.NoError(t, err)
+		require.Len(t, restored, 1)
+		assert.True(t, restored[0].DeletedAt.Valid, "returns the todo as it was before the undo")
+		after, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "undo delete", after.Title)
+	})
+
+	t.Run("status undo restores the previous workflow state", func(t *testing.T) {
+		projectID := newTestProject(t, owner, nil)
+		states := NewWorkflowRepository(db)
+		backlog := &models.WorkflowState{ProjectId: projectID, Name: "Backlog"}
+		review := &models.WorkflowState{ProjectId: projectID, Name: "Review"}
+		done := &models.WorkflowState{ProjectId: projectID, Name: "Done", Done: true}
+		for _, state := range []*models.WorkflowState{backlog, review, done} {
+			require.NoError(t, states.CreateState(owner, state))
+		}
+		todo := &models.Todo{Title: "undo status", UserId: owner, ProjectId: &projectID}
+		require.NoError(t, repo.Create(owner, todo))
+		require.NoError(t, repo.SetState(owner, todo.ID, review.ID))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Update(owner, todo.ID))
+		issue(t, owner, "undo-status", models.UndoStatus, before, time.Minute)
+
+		_, err = undo.Apply(owner, "undo-status")
+		require.NoError(t, err)
+		after, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.False(t, after.Status)
+		require.NotNil(t, after.StateId)
+		assert.Equal(t, review.ID, *after.StateId, "not the first open state")
+	})
+
+	t.Run("status undo keeps a later delete", func(t *testing.T) {
+		todo := &models.Todo{Title: "undo status then delete", UserId: owner}
+		require.NoError(t, repo.Create(owner, todo))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Update(owner, todo.ID))
+		issue(t, owner, "undo-status-deleted", models.UndoStatus, before, time.Minute)
+		require.NoError(t, repo.Delete(owner, todo.ID))
+
+		_, err = undo.Apply(owner, "undo-status-deleted")
+		require.NoError(t, err)
+		_, err = repo.GetById(owner, todo.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "the todo stays deleted")
+		var after models.Todo
+		require.NoError(t, db.Unscoped().First(&after, todo.ID).Error)
+		assert.False(t, after.Status)
+		assert.True(t, after.DeletedAt.Valid)
+	})
+
+	t.Run("tokens are bound to their user, single use and expire", func(t *testing.T) {
+		todo := &models.Todo{Title: "undo rules", UserId: owner}
+		require.NoError(t, repo.Create(owner, todo))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Update(owner, todo.ID))
+
+		issue(t, owner, "undo-once", models.UndoStatus, before, time.Minute)
+		_, err = undo.Apply(other, "undo-once")
+		assert.ErrorIs(t, err, ErrInvalidUndoToken, "another user's token")
+		_, err = undo.Apply(owner, "undo-once")
+		require.NoError(t, err)
+		_, err = undo.Apply(owner, "undo-once")
+		assert.ErrorIs(t, err, ErrInvalidUndoToken, "reused token")
+
+		require.NoError(t, repo.Update(owner, todo.ID))
+		issue(t, owner, "undo-expired", models.UndoStatus, before, -time.Second)
+		_, err = undo.Apply(owner, "undo-expired")
+		assert.ErrorIs(t, err, ErrInvalidUndoToken, "expired token")
+		after, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.True(t, after.Status, "a rejected token changes nothing")
+	})
+
+	t.Run("undo does not complete a blocked todo", func(t *testing.T) {
+		blocker := &models.Todo{Title: "undo blocker", UserId: owner}
+		todo := &models.Todo{Title: "undo blocked", UserId: owner}
+		require.NoError(t, repo.Create(owner, blocker))
+		require.NoError(t, repo.Create(owner, todo))
+		require.NoError(t, repo.Update(owner, todo.ID))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Update(owner, todo.ID))
+		require.NoError(t, repo.AddBlocker(owner, todo.ID, blocker.ID))
+		issue(t, owner, "undo-blocked", models.UndoStatus, before, time.Minute)
+
+		_, err = undo.Apply(owner, "undo-blocked")
+		assert.ErrorIs(t, err, ErrBlocked)
+		after, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.False(t, after.Status)
+
+		require.NoError(t, repo.Update(owner, blocker.ID))
+		_, err = undo.Apply(owner, "undo-blocked")
+		require.NoError(t, err, "the refused undo did not use up the token")
+		after, err = repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		assert.True(t, after.Status)
+	})
+
+	t.Run("undo requires write access", func(t *testing.T) {
+		todo := &models.Todo{Title: "undo access", UserId: owner}
+		require.NoError(t, repo.Create(owner, todo))
+		before, err := repo.GetById(owner, todo.ID)
+		require.NoError(t, err)
+		issue(t, other, "undo-foreign", models.UndoStatus, before, time.Minute)
+		_, err = undo.Apply(other, "undo-foreign")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
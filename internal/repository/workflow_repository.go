@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todolist-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+var (
+	// ErrInvalidState 工作流状态不属于待办事项所在的项目，或者待办事项不属于任何项目
+	ErrInvalidState = errors.New("state does not belong to the project of this todo")
+	// ErrStateInUse 仍有待办事项处于要删除的工作流状态
+	ErrStateInUse = errors.New("state is still used by todos")
+)
+
+// WorkflowRepository 项目的工作流状态。权限由调用方检查
+type WorkflowRepository interface {
+	WithContext(ctx context.Context) WorkflowRepository
+
+	// ListStates 按顺序返回项目的工作流状态
+	ListStates(projectID uint) ([]models.WorkflowState, error)
+	// GetState 返回项目中的工作流状态，不存在或不属于该项目时返回 gorm.ErrRecordNotFound
+	GetState(projectID, id uint) (*models.WorkflowState, error)
+	// CreateState 创建工作流状态，排在项目的最后。项目中还没有状态的待办事项
+	// 如果完成状态与新状态的 done 相同，就进入新状态
+	CreateState(uid uint, state *models.WorkflowState) error
+	// UpdateState 只更新 fields 中给出的列，done 改变时同时修改处于该状态的待办事项的完成状态。
+	// 改为完成时如果其中有待办事项还有未完成的阻塞者，不做任何修改，返回 ErrBlocked
+	UpdateState(state *models.WorkflowState, fields map[string]any) error
+	// DeleteState 删除工作流状态（软删除，保留进入该状态的记录），仍有待办事项处于该状态时返回 ErrStateInUse。
+	// 已删除的待办事项离开该状态，恢复时重新按完成状态选择
+	DeleteState(state *models.WorkflowState) error
+}
+
+type workflowRepository struct {
+	db *gorm.DB
+}
+
+func (r *workflowRepository) WithContext(ctx context.Context) WorkflowRepository {
+	return &workflowRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *workflowRepository) ListStates(projectID uint) ([]models.WorkflowState, error) {
+	var states []models.WorkflowState
+	err := r.db.Where("project_id = ?", projectID).Order("position, id").Find(&states).Error
+	return states, err
+}
+
+func (r *workflowRepository) GetState(projectID, id uint) (*models.WorkflowState, error) {
+	var state models.WorkflowState
+	if err := r.db.Clauses(dbresolver.Write).Where("project_id = ?", projectID).First(&state, id).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *workflowRepository) CreateState(uid uint, state *models.WorkflowState) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last *int
+		err := tx.Model(&models.WorkflowState{}).Where("project_id = ?", state.ProjectId).
+			Select("MAX(position)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			state.Position = *last + 1
+		}
+		if err := tx.Create(state).Error; err != nil {
+			return err
+		}
+		var ids []uint
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Todo{}).
+			Where("project_id = ? AND state_id IS NULL AND status = ?", state.ProjectId, state.Done).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("state_id", state.ID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		transitions := make([]models.TodoStateTransition, len(ids))
+		for i, id := range ids {
+			transitions[i] = models.TodoStateTransition{TodoId: id, StateId: state.ID, UserId: uid, EnteredAt: now}
+		}
+		return tx.Create(&transitions).Error
+	})
+}
+
+func (r *workflowRepository) UpdateState(state *models.WorkflowState, fields map[string]any) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(state).Updates(fields).Error; err != nil {
+			return err
+		}
+		if _, ok := fields["done"]; !ok {
+			return nil
+		}
+		// 包括已删除的待办事项，恢复后完成状态仍然与所处的状态一致
+		err := tx.Unscoped().Model(&models.Todo{}).Where("state_id = ?", state.ID).Update("status", state.Done).Error
+		if err != nil || !state.Done {
+			return err
+		}
+		// 同一状态中的阻塞者已经随之完成，只检查其他的阻塞者
+		var count int64
+		err = tx.Model(&models.TodoDependency{}).Scopes(openBlockers).
+			Where("todo_dependencies.todo_id IN (?)", tx.Unscoped().Model(&models.Todo{}).Select("id").
+				Where("state_id = ?", state.ID)).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBlocked
+		}
+		return nil
+	})
+}
+
+func (r *workflowRepository) DeleteState(state *models.WorkflowState) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Todo{}).Where("state_id = ?", state.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrStateInUse
+		}
+		err := tx.Unscoped().Model(&models.Todo{}).Where("state_id = ?", state.ID).Update("state_id", nil).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.TodoStateTransition{}).Where("state_id = ? AND left_at IS NULL", state.ID).
+			Update("left_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(state).Error
+	})
+}
+
+func NewWorkflowRepository(db *gorm.DB) WorkflowRepository {
+	return &workflowRepository{db: db}
+}
+
+// stateFor 返回项目中第一个 done 与 status 相同的工作流状态，项目为空或没有这样的状态时返回 nil
+func stateFor(tx *gorm.DB, projectID *uint, status bool) (*uint, error) {
+	if projectID == nil {
+		return nil, nil
+	}
+	var ids []uint
+	err := tx.Model(&models.WorkflowState{}).Where("project_id = ? AND done = ?", *projectID, status).
+		Order("position, id").Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return &ids[0], nil
+}
+
+// resolveState 返回待办事项恢复到 stateID 时所处的状态和完成状态。
+// stateID 为空或者已被删除时按 status 重新选择
+func resolveState(tx *gorm.DB, projectID, stateID *uint, status bool) (*uint, bool, error) {
+	if projectID != nil && stateID != nil {
+		var state models.WorkflowState
+		err := tx.Where("project_id = ?", *projectID).First(&state, *stateID).Error
+		if err == nil {
+			return &state.ID, state.Done, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+	id, err := stateFor(tx, projectID, status)
+	return id, status, err
+}
+
+// changeState 把待办事项移动到 stateID（为空表示不处于任何状态）并设置完成状态，
+// 状态改变时结束之前的停留记录并开始新的记录。todo 可以是已删除的待办事项
+func changeState(tx *gorm.DB, todo *models.Todo, stateID *uint, status bool, uid uint) error {
+	if !sameID(todo.StateId, stateID) {
+		now := time.Now()
+		err := tx.Model(&models.TodoStateTransition{}).Where("todo_id = ? AND left_at IS NULL", todo.ID).
+			Update("left_at", now).Error
+		if err != nil {
+			return err
+		}
+		if stateID != nil {
+			err := tx.Create(&models.TodoStateTransition{TodoId: todo.ID, StateId: *stateID, UserId: uid,
+				EnteredAt: now}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Unscoped().Model(todo).Updates(map[string]any{"state_id": stateID, "status": status}).Error
+}
+
+func sameID(a, b *uint) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
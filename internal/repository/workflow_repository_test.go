@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteStateKeepsTransitions(t *testing.T) {
+	states := NewWorkflowRepository(db)
+	owner := newTestUser(t, "workflow-owner")
+	projectID := newTestProject(t, owner, nil)
+	backlog := &models.WorkflowState{ProjectId: projectID, Name: "Backlog"}
+	doing := &models.WorkflowState{ProjectId: projectID, Name: "Doing"}
+	require.NoError(t, states.CreateState(owner, backlog))
+	require.NoError(t, states.CreateState(owner, doing))
+	todo := &models.Todo{Title: "workflow", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, todo))
+	require.NoError(t, repo.SetState(owner, todo.ID, doing.ID))
+
+	assert.ErrorIs(t, states.DeleteState(doing), ErrStateInUse)
+	require.NoError(t, states.DeleteState(backlog))
+
+	list, err := states.ListStates(projectID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, doing.ID, list[0].ID)
+
+	transitions, err := repo.StateTransitions(todo.ID)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	require.NotNil(t, transitions[0].State)
+	assert.Equal(t, "Backlog", transitions[0].State.Name)
+	assert.True(t, transitions[0].State.DeletedAt.Valid)
+	assert.NotNil(t, transitions[0].LeftAt)
+	assert.ErrorIs(t, repo.SetState(owner, todo.ID, backlog.ID), ErrInvalidState, "deleted states cannot be entered")
+}
+
+func TestUpdateStateRespectsBlockers(t *testing.T) {
+	states := NewWorkflowRepository(db)
+	owner := newTestUser(t, "workflow-blocked")
+	projectID := newTestProject(t, owner, nil)
+	backlog := &models.WorkflowState{ProjectId: projectID, Name: "Backlog"}
+	review := &models.WorkflowState{ProjectId: projectID, Name: "Review"}
+	require.NoError(t, states.CreateState(owner, backlog))
+	require.NoError(t, states.CreateState(owner, review))
+	blocker := &models.Todo{Title: "blocker", UserId: owner, ProjectId: &projectID}
+	blocked := &models.Todo{Title: "blocked", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, blocker))
+	require.NoError(t, repo.Create(owner, blocked))
+	require.NoError(t, repo.SetState(owner, blocked.ID, review.ID))
+	require.NoError(t, repo.AddBlocker(owner, blocked.ID, blocker.ID))
+
+	assert.ErrorIs(t, states.UpdateState(review, map[string]any{"done": true}), ErrBlocked)
+	state, err := states.GetState(projectID, review.ID)
+	require.NoError(t, err)
+	assert.False(t, state.Done, "a refused update changes nothing")
+	todo, err := repo.GetById(owner, blocked.ID)
+	require.NoError(t, err)
+	assert.False(t, todo.Status)
+
+	// 阻塞者在同一个状态中时会一起完成
+	require.NoError(t, repo.SetState(owner, blocker.ID, review.ID))
+	require.NoError(t, states.UpdateState(state, map[string]any{"done": true}))
+	todo, err = repo.GetById(owner, blocked.ID)
+	require.NoError(t, err)
+	assert.True(t, todo.Status)
+}
+
+func TestConcurrentToggleKeepsOneOpenTransition(t *testing.T) {
+	states := NewWorkflowRepository(db)
+	owner := newTestUser(t, "workflow-concurrent")
+	projectID := newTestProject(t, owner, nil)
+	require.NoError(t, states.CreateState(owner, &models.WorkflowState{ProjectId: projectID, Name: "Open"}))
+	require.NoError(t, states.CreateState(owner, &models.WorkflowState{ProjectId: projectID, Name: "Done", Done: true}))
+	todo := &models.Todo{Title: "concurrent", UserId: owner, ProjectId: &projectID}
+	require.NoError(t, repo.Create(owner, todo))
+
+	const toggles = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, toggles)
+	for range toggles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Update(owner, todo.ID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	after, err := repo.GetById(owner, todo.ID)
+	require.NoError(t, err)
+	assert.False(t, after.Status, "no toggle is lost")
+	var open int64
+	require.NoError(t, db.Model(&models.TodoStateTransition{}).Where("todo_id = ? AND left_at IS NULL", todo.ID).
+		Count(&open).Error)
+	assert.EqualValues(t, 1, open)
+}
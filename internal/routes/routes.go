@@ -4,34 +4,136 @@ package routes
 import (
 	"todolist-api/internal/handlers"
 	"todolist-api/internal/middleware"
+	"todolist-api/internal/ratelimit"
 	"todolist-api/internal/services"
+	"todolist-api/pkg/config"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置所有应用的路由
-func SetupRoutes(router *gin.Engine, todoHandler *handlers.TodoHandler,
-	userHandler *handlers.UserHandler, service *services.AuthService) {
+func SetupRoutes(router *gin.Engine, todoHandler *handlers.TodoHandler, commentHandler *handlers.CommentHandler,
+	attachmentHandler *handlers.AttachmentHandler, auditHandler *handlers.AuditHandler, undoHandler *handlers.UndoHandler,
+	projectHandler *handlers.ProjectHandler, userHandler *handlers.UserHandler, tokenHandler *handlers.TokenHandler,
+	oidcHandler *handlers.OIDCHandler, wellKnownHandler *handlers.WellKnownHandler,
+	healthHandler *handlers.HealthHandler, service *services.AuthService,
+	tokenService *services.PersonalTokenService, adminCfg *config.AdminConfig,
+	limiter *ratelimit.Limiter) {
+	// 负载均衡和容器编排使用的存活、就绪检查
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// 供其他服务校验令牌的公钥
+	router.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+
 	// 创建一个路由组 /api/v1
 	api := router.Group("/api/v1")
 	public := api.Group("/user")
+	public.Use(middleware.RateLimit(limiter, "public"))
 	{
 		public.POST("/register", userHandler.Register)
 		public.POST("/login", userHandler.Login)
+		public.POST("/login/2fa", userHandler.LoginTwoFactor)
+		public.POST("/password/forgot", userHandler.ForgotPassword)
+		public.POST("/password/reset", userHandler.ResetPassword)
+		// 未启用 OIDC 时不注册外部登录的路由
+		if oidcHandler != nil {
+			public.GET("/oidc/login", oidcHandler.Login)
+			public.GET("/oidc/callback", oidcHandler.Callback)
+		}
 	}
 
 	// 受保护的路由
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware(service))
+	// 限流在认证之后，以便按 uid 计数
+	protected.Use(middleware.AuthMiddleware(service, tokenService), middleware.RateLimit(limiter, "protected"))
 	{
-		// 为 todos 创建一个子路由组
+		// 账户管理接口只允许登录会话访问，个人访问令牌不能使用
+		account := protected.Group("/user")
+		account.Use(middleware.RequireSession())
+		{
+			account.POST("/password", userHandler.ChangePassword)
+
+			// 两步验证的管理接口
+			twoFactorRoutes := account.Group("/2fa")
+			{
+				twoFactorRoutes.POST("/enroll", userHandler.EnrollTOTP)
+				twoFactorRoutes.POST("/confirm", userHandler.ConfirmTOTP)
+				twoFactorRoutes.POST("/disable", userHandler.DisableTOTP)
+				twoFactorRoutes.POST("/recovery-codes", userHandler.RegenerateRecoveryCodes)
+			}
+
+			// 个人访问令牌
+			tokenRoutes := account.Group("/tokens")
+			{
+				tokenRoutes.POST("", tokenHandler.CreateToken)
+				tokenRoutes.GET("", tokenHandler.ListTokens)
+				tokenRoutes.DELETE("/:id", tokenHandler.RevokeToken)
+			}
+		}
+
+		// 为 todos 创建一个子路由组，个人访问令牌需要具有相应的权限范围
+		read := middleware.RequireScope(services.ScopeTodosRead)
+		write := middleware.RequireScope(services.ScopeTodosWrite)
 		todoRoutes := protected.Group("/todos")
 		{
-			todoRoutes.POST("", todoHandler.CreateTodo)
-			todoRoutes.GET("", todoHandler.GetAllTodos)
-			todoRoutes.GET("/:id", todoHandler.GetTodoById)
-			todoRoutes.PUT("/:id", todoHandler.UpdateTodo)
-			todoRoutes.DELETE("/:id", todoHandler.DeleteTodo)
+			todoRoutes.POST("", write, todoHandler.CreateTodo)
+			todoRoutes.GET("", read, todoHandler.GetAllTodos)
+			todoRoutes.GET("/graph", read, todoHandler.DependencyGraph)
+			todoRoutes.GET("/:id", read, todoHandler.GetTodoById)
+			todoRoutes.PUT("/:id", write, todoHandler.UpdateTodo)
+			todoRoutes.DELETE("/:id", write, todoHandler.DeleteTodo)
+			todoRoutes.PUT("/:id/assignee", write, todoHandler.AssignTodo)
+			todoRoutes.POST("/:id/move", write, todoHandler.MoveTodo)
+			todoRoutes.PUT("/:id/state", write, todoHandler.SetTodoState)
+			todoRoutes.GET("/:id/state-history", read, todoHandler.TodoStateHistory)
+			todoRoutes.GET("/:id/blockers", read, todoHandler.ListBlockers)
+			todoRoutes.POST("/:id/blockers", write, todoHandler.AddBlocker)
+			todoRoutes.DELETE("/:id/blockers/:bid", write, todoHandler.RemoveBlocker)
+
+			todoRoutes.GET("/:id/comments", read, commentHandler.ListComments)
+			todoRoutes.POST("/:id/comments", write, commentHandler.CreateComment)
+			todoRoutes.PUT("/:id/comments/:cid", write, commentHandler.UpdateComment)
+			todoRoutes.DELETE("/:id/comments/:cid", write, commentHandler.DeleteComment)
+
+			todoRoutes.GET("/:id/attachments", read, attachmentHandler.ListAttachments)
+			todoRoutes.POST("/:id/attachments", write, attachmentHandler.UploadAttachment)
+			todoRoutes.GET("/:id/attachments/:aid", read, attachmentHandler.DownloadAttachment)
+			todoRoutes.DELETE("/:id/attachments/:aid", write, attachmentHandler.DeleteAttachment)
+
+			todoRoutes.GET("/:id/history", read, auditHandler.TodoHistory)
+		}
+
+		// 撤销删除、修改完成状态等操作
+		protected.POST("/undo/:token", write, undoHandler.Undo)
+
+		// 共享项目和成员管理，与待办事项使用相同的权限范围
+		projectRoutes := protected.Group("/projects")
+		{
+			projectRoutes.POST("", write, projectHandler.CreateProject)
+			projectRoutes.GET("", read, projectHandler.ListProjects)
+			projectRoutes.GET("/:id", read, projectHandler.GetProject)
+			projectRoutes.POST("/:id/members", write, projectHandler.InviteMember)
+			projectRoutes.PUT("/:id/members/:uid", write, projectHandler.UpdateMember)
+			projectRoutes.DELETE("/:id/members/:uid", write, projectHandler.RemoveMember)
+
+			projectRoutes.GET("/:id/states", read, projectHandler.ListStates)
+			projectRoutes.POST("/:id/states", write, projectHandler.CreateState)
+			projectRoutes.PUT("/:id/states/:sid", write, projectHandler.UpdateState)
+			projectRoutes.DELETE("/:id/states/:sid", write, projectHandler.DeleteState)
+		}
+		invitationRoutes := protected.Group("/invitations")
+		{
+			invitationRoutes.GET("", read, projectHandler.ListInvitations)
+			invitationRoutes.POST("/:id/accept", write, projectHandler.AcceptInvitation)
+			invitationRoutes.POST("/:id/decline", write, projectHandler.DeclineInvitation)
+		}
+
+		// 管理接口只允许管理员的登录会话访问
+		adminRoutes := protected.Group("/admin")
+		adminRoutes.Use(middleware.RequireSession(), middleware.RequireAdmin(adminCfg))
+		{
+			adminRoutes.GET("/audit", auditHandler.ListAuditEvents)
 		}
 	}
 }
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"todolist-api/pkg/config"
+)
+
+// New 根据配置创建 http.Server，未配置的超时使用下面的默认值，避免慢速客户端长期占用连接
+func New(cfg *config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeoutSeconds, 10),
+		ReadTimeout:       seconds(cfg.ReadTimeoutSeconds, 30),
+		WriteTimeout:      seconds(cfg.WriteTimeoutSeconds, 30),
+		IdleTimeout:       seconds(cfg.IdleTimeoutSeconds, 120),
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// ListenAndServe 配置了证书时使用 HTTPS，正常关闭时返回 http.ErrServerClosed
+func ListenAndServe(srv *http.Server, cfg *config.ServerConfig) error {
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		return srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return srv.ListenAndServe()
+}
+
+func seconds(value, fallback int) time.Duration {
+	if value == 0 {
+		value = fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+// Workers 管理后台任务，关闭时取消它们的 context 并等待退出
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+// Go 在新的 goroutine 中运行 fn，fn 应在 ctx 取消后尽快返回
+func (w *Workers) Go(fn func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		fn(w.ctx)
+	}()
+}
+
+// Stop 通知所有后台任务退出并等待，ctx 到期时不再等待
+func (w *Workers) Stop(ctx context.Context) error {
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not stop: %w", ctx.Err())
+	}
+}
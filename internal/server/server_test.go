@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	srv := New(&config.ServerConfig{Port: 4000, ReadTimeoutSeconds: 5, MaxHeaderBytes: 4096}, http.NotFoundHandler())
+	assert.Equal(t, ":4000", srv.Addr)
+	assert.Equal(t, 5*time.Second, srv.ReadTimeout)
+	// 未配置时使用默认值
+	assert.Equal(t, 10*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 30*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 4096, srv.MaxHeaderBytes)
+}
+
+func TestWorkersStop(t *testing.T) {
+	w := NewWorkers()
+	stopped := make(chan struct{})
+	w.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	assert.NoError(t, w.Stop(context.Background()))
+	<-stopped
+
+	// 不响应取消的任务在超时后不再等待
+	w = NewWorkers()
+	block := make(chan struct{})
+	defer close(block)
+	w.Go(func(context.Context) { <-block })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Stop(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+)
+
+// AuditLog 记录修改操作的审计事件，并按保留期清理旧的事件
+type AuditLog struct {
+	events repository.AuditRepository
+}
+
+func NewAuditLog(events repository.AuditRepository) *AuditLog {
+	return &AuditLog{events: events}
+}
+
+// Record 保存审计事件。操作已经完成，写入失败只记录日志，不影响请求结果；
+// 客户端断开连接也不会中断写入
+func (a *AuditLog) Record(ctx context.Context, event *models.AuditEvent) {
+	ctx = context.WithoutCancel(ctx)
+	if err := a.events.WithContext(ctx).Create(event); err != nil {
+		slog.ErrorContext(ctx, "failed to record audit event", slog.String("action", event.Action),
+			slog.String("entity_type", event.EntityType), slog.Uint64("entity_id", uint64(event.EntityId)),
+			slog.Any("error", err))
+	}
+}
+
+// Run 每隔 interval 删除一次超过 retention 的事件，直到 ctx 取消
+func (a *AuditLog) Run(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := a.events.WithContext(ctx).DeleteBefore(time.Now().Add(-retention))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to delete expired audit events", slog.Any("error", err))
+		}
+		if deleted > 0 {
+			slog.InfoContext(ctx, "deleted expired audit events", slog.Int64("count", deleted))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// auditIgnoredFields 不记录在变化中的字段：主键、时间戳和查询时统计的字段
+var auditIgnoredFields = map[string]bool{
+	"ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true, "comment_count": true, "blocked": true,
+	"todos": true,
+}
+
+// Diff 按 JSON 字段比较 before 和 after，返回变化的字段。before 为 nil 表示创建，after 为 nil 表示删除。
+// 不会出现在 JSON 中的字段（如密码）不会被记录
+func Diff(before, after any) map[string]models.AuditChange {
+	b, a := toFields(before), toFields(after)
+	changes := make(map[string]models.AuditChange)
+	for key, value := range b {
+		if !auditIgnoredFields[key] && !reflect.DeepEqual(value, a[key]) {
+			changes[key] = models.AuditChange{Before: value, After: a[key]}
+		}
+	}
+	for key, value := range a {
+		if _, ok := b[key]; !ok && !auditIgnoredFields[key] && value != nil {
+			changes[key] = models.AuditChange{After: value}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// toFields 把结构体转换为 JSON 字段和值
+func toFields(v any) map[string]any {
+	fields := map[string]any{}
+	if rv := reflect.ValueOf(v); !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
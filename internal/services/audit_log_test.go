@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	assignee := uint(2)
+	before := &models.Todo{Title: "write docs", UserId: 1}
+	before.ID = 7
+	after := *before
+	after.Status = true
+	after.AssigneeId = &assignee
+	after.CommentCount = 3
+
+	assert.Equal(t, map[string]models.AuditChange{
+		"status":      {Before: false, After: true},
+		"assignee_id": {Before: nil, After: float64(2)},
+	}, Diff(before, &after))
+	assert.Nil(t, Diff(before, before))
+
+	created := Diff(nil, before)
+	assert.Equal(t, models.AuditChange{After: "write docs"}, created["title"])
+	assert.NotContains(t, created, "ID")
+	assert.NotContains(t, created, "project_id", "null fields are not recorded on create")
+
+	deleted := Diff(before, nil)
+	assert.Equal(t, models.AuditChange{Before: "write docs"}, deleted["title"])
+
+	// 不出现在 JSON 中的字段不会被记录
+	assert.Nil(t, Diff(&models.User{Username: "bob", Password: "a"}, &models.User{Username: "bob", Password: "b"}))
+}
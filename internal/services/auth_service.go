@@ -1,49 +1,172 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/tracing"
 	"todolist-api/pkg/config"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+const (
+	// ScopeTwoFactor 两步验证挑战令牌的作用域，只能用于换取正式令牌
+	ScopeTwoFactor = "2fa"
+	// challengeTTL 挑战令牌的有效期
+	challengeTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidScope = errors.New("invalid token scope")
+	ErrTokenRevoked = errors.New("token has been revoked")
+)
+
+// UserLookup 用于校验令牌时查询用户当前的令牌版本
+type UserLookup interface {
+	GetUserById(id uint) (*models.User, error)
+}
+
 type Claims struct {
 	UserId uint `json:"uid"`
+	// Version 签发时用户的令牌版本，与用户当前版本不一致说明令牌已失效
+	Version uint `json:"ver"`
+	// Scope 为空表示正式的访问令牌
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
+
+// defaultIssuer 未配置 issuer 时使用的 iss
+const defaultIssuer = "todolist"
+
 type AuthService struct {
 	cfg *config.JWTConfig
+	// keys 为空时使用 cfg.Secret 进行 HS256 签名
+	keys  *KeySet
+	users UserLookup
+}
+
+func NewAuthService(cfg *config.JWTConfig, keys *KeySet, users UserLookup) *AuthService {
+	return &AuthService{cfg: cfg, keys: keys, users: users}
+}
+
+// JWKS 返回用于校验令牌的公钥集合，未配置非对称密钥时为空
+func (s *AuthService) JWKS() JWKS {
+	if s.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) GenerateToken(user *models.User) (string, error) {
+	return s.sign(user, "", time.Duration(s.cfg.ExpireHours)*time.Hour)
+}
+
+// GenerateChallengeToken 生成登录时两步验证使用的短期挑战令牌
+func (s *AuthService) GenerateChallengeToken(user *models.User) (string, error) {
+	return s.sign(user, ScopeTwoFactor, challengeTTL)
+}
+
+func (s *AuthService) VerifyToken(ctx context.Context, tokenString string) (*Claims, error) {
+	_, span := tracing.Tracer().Start(ctx, "AuthService.VerifyToken")
+	defer span.End()
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	// 挑战令牌不能当作访问令牌使用
+	if claims.Scope != "" {
+		span.SetStatus(codes.Error, ErrInvalidScope.Error())
+		return nil, ErrInvalidScope
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(claims.UserId)))
+	return claims, nil
 }
 
-func NewAuthService(cfg *config.JWTConfig) *AuthService {
-	return &AuthService{cfg: cfg}
+// VerifyChallengeToken 校验两步验证的挑战令牌
+func (s *AuthService) VerifyChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scope != ScopeTwoFactor {
+		return nil, ErrInvalidScope
+	}
+	return claims, nil
 }
 
-func (s *AuthService) GenerateToken(uid uint) (string, error) {
-	claims := Claims{UserId: uid, RegisteredClaims: jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.cfg.ExpireHours) * time.Hour)),
+func (s *AuthService) sign(user *models.User, scope string, ttl time.Duration) (string, error) {
+	claims := Claims{UserId: user.ID, Version: user.TokenVersion, Scope: scope, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		NotBefore: jwt.NewNumericDate(time.Now()),
-		Issuer:    "todolist",
+		Issuer:    s.issuer(),
+		Audience:  s.cfg.Audience,
 	},
 	}
+	if s.keys != nil {
+		token := jwt.NewWithClaims(s.keys.active.method, claims)
+		token.Header["kid"] = s.keys.active.kid
+		return token.SignedString(s.keys.active.private)
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.cfg.Secret))
 }
 
-func (s *AuthService) VerifyToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// keyFunc 根据令牌头中的算法和 kid 选择校验密钥
+func (s *AuthService) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if s.cfg.Secret == "" {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(s.cfg.Secret), nil
-	})
+	}
+	if s.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	return s.keys.lookup(kid, token.Method)
+}
+
+func (s *AuthService) issuer() string {
+	if s.cfg.Issuer != "" {
+		return s.cfg.Issuer
+	}
+	return defaultIssuer
+}
+
+func (s *AuthService) parse(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg(),
+		}),
+	}
+	if s.cfg.ValidateIssuer {
+		opts = append(opts, jwt.WithIssuer(s.issuer()))
+	}
+	if s.cfg.ValidateAudience {
+		// aud 中包含任意一个配置的受众即可
+		opts = append(opts, jwt.WithAudience(s.cfg.Audience...))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.keyFunc, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if s.users != nil {
+		// 修改密码后令牌版本递增，旧令牌随之失效
+		user, err := s.users.GetUserById(claims.UserId)
+		if err != nil || user.TokenVersion != claims.Version {
+			return nil, ErrTokenRevoked
+		}
 	}
-	return nil, err
+	return claims, nil
 }
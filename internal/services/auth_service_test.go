@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeys 生成私钥和对应的公钥文件，返回两个文件的路径
+func writeKeys(t *testing.T, name string, priv any, pub any) (string, string) {
+	dir := t.TempDir()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644))
+	return privPath, pubPath
+}
+
+func newAuthService(t *testing.T, cfg *config.JWTConfig) *AuthService {
+	keys, err := LoadKeySet(cfg)
+	require.NoError(t, err)
+	return NewAuthService(cfg, keys, nil)
+}
+
+func TestAuthServiceKeyRotation(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldPriv, oldPub := writeKeys(t, "old", rsaPriv, &rsaPriv.PublicKey)
+	newPriv, _ := writeKeys(t, "new", edPriv, edPub)
+	user := &models.User{}
+	user.ID = 7
+
+	// 轮换前：使用 RSA 密钥签发
+	before := newAuthService(t, &config.JWTConfig{
+		ExpireHours: 1,
+		Keys:        []config.JWTKeyConfig{{Kid: "old", PrivateKeyFile: oldPriv}},
+		ActiveKid:   "old",
+	})
+	oldToken, err := before.GenerateToken(user)
+	require.NoError(t, err)
+
+	// 轮换后：新令牌使用 Ed25519 签发，旧密钥只保留公钥用于校验
+	after := newAuthService(t, &config.JWTConfig{
+		ExpireHours: 1,
+		Keys: []config.JWTKeyConfig{
+			{Kid: "new", PrivateKeyFile: newPriv},
+			{Kid: "old", PublicKeyFile: oldPub},
+		},
+		ActiveKid: "new",
+	})
+	newToken, err := after.GenerateToken(user)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	assert.Equal(t, "new", parsed.Header["kid"])
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, err := after.VerifyToken(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), claims.UserId)
+	}
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "new", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+
+	t.Run("Unknown kid is rejected", func(t *testing.T) {
+		only := newAuthService(t, &config.JWTConfig{
+			Keys:      []config.JWTKeyConfig{{Kid: "new", PrivateKeyFile: newPriv}},
+			ActiveKid: "new",
+		})
+		_, err := only.VerifyToken(context.Background(), oldToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("HS256 is rejected without a secret", func(t *testing.T) {
+		hs := NewAuthService(&config.JWTConfig{Secret: "secret", ExpireHours: 1}, nil, nil)
+		token, err := hs.GenerateToken(user)
+		require.NoError(t, err)
+		_, err = after.VerifyToken(context.Background(), token)
+		assert.Error(t, err)
+	})
+
+	t.Run("Active key must have a private key", func(t *testing.T) {
+		_, err := LoadKeySet(&config.JWTConfig{
+			Keys:      []config.JWTKeyConfig{{Kid: "old", PublicKeyFile: oldPub}},
+			ActiveKid: "old",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestAuthServiceIssuerAndAudience(t *testing.T) {
+	user := &models.User{}
+	issuing := NewAuthService(&config.JWTConfig{Secret: "secret", ExpireHours: 1, Issuer: "other"}, nil, nil)
+	token, err := issuing.GenerateToken(user)
+	require.NoError(t, err)
+
+	strict := NewAuthService(&config.JWTConfig{
+		Secret:           "secret",
+		ExpireHours:      1,
+		Audience:         []string{"todolist-api"},
+		ValidateIssuer:   true,
+		ValidateAudience: true,
+	}, nil, nil)
+	_, err = strict.VerifyToken(context.Background(), token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	own, err := strict.GenerateToken(user)
+	require.NoError(t, err)
+	_, err = strict.VerifyToken(context.Background(), own)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"todolist-api/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey 一个非对称密钥，private 为空表示只用于校验
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet 非对称签名密钥集合，active 用于签发，keys 中的所有密钥都可以用于校验
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	// order 保持配置中的顺序，使 JWKS 输出稳定
+	order []string
+}
+
+// JWK RFC 7517 格式的公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet 从配置加载非对称密钥，没有配置密钥时返回 nil，此时使用 HS256
+func LoadKeySet(cfg *config.JWTConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+	ks := &KeySet{keys: make(map[string]*signingKey, len(cfg.Keys))}
+	for _, kc := range cfg.Keys {
+		if kc.Kid == "" {
+			return nil, errors.New("jwt key without kid")
+		}
+		if _, ok := ks.keys[kc.Kid]; ok {
+			return nil, fmt.Errorf("duplicate jwt kid %q", kc.Kid)
+		}
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.Kid, err)
+		}
+		ks.keys[kc.Kid] = key
+		ks.order = append(ks.order, kc.Kid)
+	}
+	active, ok := ks.keys[cfg.ActiveKid]
+	if !ok {
+		return nil, fmt.Errorf("active_kid %q is not one of the configured jwt keys", cfg.ActiveKid)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", cfg.ActiveKid)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+func loadKey(kc config.JWTKeyConfig) (*signingKey, error) {
+	key := &signingKey{kid: kc.Kid}
+	switch {
+	case kc.PrivateKeyFile != "":
+		block, err := readPEM(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			// 兼容 openssl genrsa 生成的 PKCS#1 格式
+			if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+				priv = rsaKey
+			} else {
+				return nil, fmt.Errorf("parse private key: %w", err)
+			}
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case kc.PublicKeyFile != "":
+		block, err := readPEM(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		key.public = pub
+	default:
+		return nil, errors.New("either private_key_file or public_key_file is required")
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", pub)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
+
+// lookup 返回用于校验指定 kid 的密钥，算法必须与密钥类型一致，防止算法混淆攻击
+func (ks *KeySet) lookup(kid string, method jwt.SigningMethod) (crypto.PublicKey, error) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if key.method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for kid %q", method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWKS 返回所有密钥的公钥，包括只用于校验的旧密钥
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
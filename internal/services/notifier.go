@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+	"todolist-api/pkg/mailer"
+)
+
+// Notifier 通过邮件通知用户待办事项的变化。用户没有设置邮箱时只记录日志，发送失败不影响请求结果。
+// 请求中使用时 mailer 应该是 mailer.Async，SMTP 服务器的延迟不会拖慢请求
+type Notifier struct {
+	users  repository.TodoRepository
+	mailer mailer.Mailer
+}
+
+func NewNotifier(users repository.TodoRepository, mailer mailer.Mailer) *Notifier {
+	return &Notifier{users: users, mailer: mailer}
+}
+
+// TodoAssigned 通知被指派的用户。自己指派给自己时不通知
+func (n *Notifier) TodoAssigned(ctx context.Context, todo *models.Todo, by uint) {
+	if todo.AssigneeId == nil || *todo.AssigneeId == by {
+		return
+	}
+	actor := n.username(ctx, by)
+	n.notify(ctx, *todo.AssigneeId, "notify todo assigned", fmt.Sprintf("%s assigned a todo to you", actor),
+		fmt.Sprintf("%s assigned \"%s\" to you.\n", actor, todo.Title))
+}
+
+// Mentioned 通知评论中提及的用户，不通知评论的作者。每个用户一封邮件，都在后台发送，不会逐封阻塞请求
+func (n *Notifier) Mentioned(ctx context.Context, todo *models.Todo, comment *models.Comment, uids []uint) {
+	if len(uids) == 0 {
+		return
+	}
+	actor := n.username(ctx, comment.UserId)
+	for _, uid := range uids {
+		if uid == comment.UserId {
+			continue
+		}
+		n.notify(ctx, uid, "notify comment mention", fmt.Sprintf("%s mentioned you in a comment", actor),
+			fmt.Sprintf("%s mentioned you on \"%s\":\n\n%s\n", actor, todo.Title, comment.Body))
+	}
+}
+
+func (n *Notifier) username(ctx context.Context, uid uint) string {
+	user, err := n.users.WithContext(ctx).GetUserById(uid)
+	if err != nil {
+		return "Someone"
+	}
+	return user.Username
+}
+
+// notify 给用户发送邮件，event 用于日志
+func (n *Notifier) notify(ctx context.Context, uid uint, event, subject, body string) {
+	user, err := n.users.WithContext(ctx).GetUserById(uid)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load user for notification", slog.String("event", event),
+			slog.Uint64("target_user_id", uint64(uid)), slog.Any("error", err))
+		return
+	}
+	if user.Email == nil || *user.Email == "" {
+		slog.DebugContext(ctx, "user has no email, notification skipped", slog.String("event", event),
+			slog.Uint64("target_user_id", uint64(uid)))
+		return
+	}
+	msg := mailer.Message{To: *user.Email, Subject: subject, Body: fmt.Sprintf("Hi %s,\n\n%s", user.Username, body)}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "failed to send notification", slog.String("event", event),
+			slog.Uint64("target_user_id", uint64(uid)), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/config"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcStateTTL 从跳转到身份提供方到回调之间允许的最长时间
+	oidcStateTTL = 10 * time.Minute
+	// 自动创建的用户名长度与注册接口的限制保持一致
+	usernameMinLen = 4
+	usernameMaxLen = 20
+)
+
+var (
+	ErrOIDCState = errors.New("invalid oidc state")
+	ErrOIDCNonce = errors.New("invalid oidc nonce")
+
+	usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// IdentityStore 保存外部身份与本地用户的关联，由 repository.IdentityRepository 实现
+type IdentityStore interface {
+	GetUserByIdentity(issuer, subject string) (*models.User, error)
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+}
+
+// oidcStateClaims 保存在浏览器 cookie 中的登录状态，签名防止被篡改
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// oidcIDClaims ID Token 中用到的字段
+type oidcIDClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// OIDCService 实现 OpenID Connect 授权码 + PKCE 登录流程
+type OIDCService struct {
+	cfg      *config.OIDCConfig
+	stateKey []byte
+	store    IdentityStore
+
+	// 身份提供方的端点在第一次使用时发现，避免启动时身份提供方不可用导致服务无法启动
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCService(cfg *config.OIDCConfig, stateKey []byte, store IdentityStore) *OIDCService {
+	return &OIDCService{cfg: cfg, stateKey: stateKey, store: store}
+}
+
+func (s *OIDCService) discover(ctx context.Context) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	// provider 会保留 ctx 用于之后刷新 JWKS，不能使用会随请求结束而取消的 ctx
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), s.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+func (s *OIDCService) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range s.cfg.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+// AuthCodeURL 返回身份提供方的授权地址，以及需要保存在 cookie 中、回调时原样带回的状态
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (string, string, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	claims := oidcStateClaims{State: state, Nonce: nonce, Verifier: verifier, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateTTL)),
+	}}
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.stateKey)
+	if err != nil {
+		return "", "", err
+	}
+	url := s.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return url, cookie, nil
+}
+
+// Exchange 处理回调：校验状态，用授权码换取并校验 ID Token，返回关联的本地用户（首次登录时自动创建）。
+// 本次登录新建了关联时同时返回该关联，否则为 nil
+func (s *OIDCService) Exchange(ctx context.Context, code, state, cookie string) (*models.User, *models.UserIdentity, error) {
+	var claims oidcStateClaims
+	_, err := jwt.ParseWithClaims(cookie, &claims, func(token *jwt.Token) (any, error) {
+		return s.stateKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.State == "" || claims.State != state {
+		return nil, nil, ErrOIDCState
+	}
+
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := s.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(claims.Verifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errors.New("oidc token response has no id_token")
+	}
+	// 校验签名（JWKS）、iss、aud 和过期时间
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	var idClaims oidcIDClaims
+	if err := idToken.Claims(&idClaims); err != nil {
+		return nil, nil, err
+	}
+	if idClaims.Nonce != claims.Nonce {
+		return nil, nil, ErrOIDCNonce
+	}
+	return s.linkUser(idToken.Issuer, idToken.Subject, &idClaims)
+}
+
+// linkUser 返回与外部身份关联的用户，不存在时创建新用户并返回新建的关联
+func (s *OIDCService) linkUser(issuer, subject string, claims *oidcIDClaims) (*models.User, *models.UserIdentity, error) {
+	user, err := s.store.GetUserByIdentity(issuer, subject)
+	if err == nil {
+		return user, nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	// 外部账户不使用本地密码登录，设置一个随机密码占位
+	password, err := randomString()
+	if err != nil {
+		return nil, nil, err
+	}
+	base := usernameFromClaims(claims)
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := randomString()
+			if err != nil {
+				return nil, nil, err
+			}
+			username = truncate(base, usernameMaxLen-5) + "_" + suffix[:4]
+		}
+		user = &models.User{Username: username, Password: password}
+		identity := &models.UserIdentity{Issuer: issuer, Subject: subject, Email: claims.Email}
+		err = s.store.CreateUserWithIdentity(user, identity)
+		if err == nil {
+			return user, identity, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, nil, err
+		}
+		// 同一身份并发首次登录时，另一个请求可能已经完成了关联
+		if user, err := s.store.GetUserByIdentity(issuer, subject); err == nil {
+			return user, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("could not allocate a username for %q", base)
+}
+
+// usernameFromClaims 根据 preferred_username 或邮箱生成符合注册规则的用户名
+func usernameFromClaims(claims *oidcIDClaims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	name = usernameInvalidChars.ReplaceAllString(name, "")
+	if len(name) < usernameMinLen {
+		name = "user" + name
+	}
+	return truncate(name, usernameMaxLen)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// mockProvider 一个最小的本地 OIDC 身份提供方，支持发现、JWKS 和授权码换取令牌
+type mockProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	subject  string
+	username string
+	// 授权码对应的 nonce 和 PKCE challenge
+	nonce     string
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &mockProvider{key: key, subject: "u-123", username: "alice"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":                p.server.URL,
+			"sub":                p.subject,
+			"aud":                "todolist",
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"nonce":              p.nonce,
+			"preferred_username": p.username,
+			"email":              "alice@example.com",
+		})
+		token.Header["kid"] = "test"
+		idToken, _ := token.SignedString(key)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize 模拟浏览器跳转到身份提供方并完成登录
+func (p *mockProvider) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	p.nonce = q.Get("nonce")
+	p.challenge = q.Get("code_challenge")
+	return q.Get("state")
+}
+
+// memoryIdentityStore 内存中的 IdentityStore
+type memoryIdentityStore struct {
+	users      map[string]*models.User
+	usernames  map[string]bool
+	nextUserId uint
+}
+
+func (m *memoryIdentityStore) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	if u, ok := m.users[issuer+"|"+subject]; ok {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryIdentityStore) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	if m.usernames[user.Username] {
+		return gorm.ErrDuplicatedKey
+	}
+	m.nextUserId++
+	user.ID = m.nextUserId
+	identity.UserId = user.ID
+	m.usernames[user.Username] = true
+	m.users[identity.Issuer+"|"+identity.Subject] = user
+	return nil
+}
+
+func TestOIDCService(t *testing.T) {
+	p := newMockProvider(t)
+	store := &memoryIdentityStore{users: map[string]*models.User{}, usernames: map[string]bool{"alice": true}}
+	svc := NewOIDCService(&config.OIDCConfig{
+		Issuer:      p.server.URL,
+		ClientID:    "todolist",
+		RedirectURL: "http://localhost/callback",
+	}, []byte("state-key"), store)
+	ctx := context.Background()
+
+	t.Run("First login creates a user", func(t *testing.T) {
+		authURL, cookie, err := svc.AuthCodeURL(ctx)
+		require.NoError(t, err)
+		state := p.authorize(t, authURL)
+
+		user, identity, err := svc.Exchange(ctx, "good-code", state, cookie)
+		require.NoError(t, err)
+		// alice 已被占用，自动追加后缀
+		assert.Regexp(t, `^alice_[0-9a-f]{4}$`, user.Username)
+		assert.Equal(t, uint(1), user.ID)
+		require.NotNil(t, identity, "a new link is reported for auditing")
+		assert.Equal(t, user.ID, identity.UserId)
+	})
+
+	t.Run("Second login reuses the linked user", func(t *testing.T) {
+		authURL, cookie, _ := svc.AuthCodeURL(ctx)
+		state := p.authorize(t, authURL)
+
+		user, identity, err := svc.Exchange(ctx, "good-code", state, cookie)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), user.ID)
+		assert.Nil(t, identity)
+	})
+
+	t.Run("State mismatch is rejected", func(t *testing.T) {
+		authURL, cookie, _ := svc.AuthCodeURL(ctx)
+		p.authorize(t, authURL)
+
+		_, _, err := svc.Exchange(ctx, "good-code", "forged", cookie)
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+
+	t.Run("Nonce mismatch is rejected", func(t *testing.T) {
+		authURL, cookie, _ := svc.AuthCodeURL(ctx)
+		state := p.authorize(t, authURL)
+		p.nonce = "replayed"
+
+		_, _, err := svc.Exchange(ctx, "good-code", state, cookie)
+		assert.ErrorIs(t, err, ErrOIDCNonce)
+	})
+
+	t.Run("Wrong PKCE verifier is rejected", func(t *testing.T) {
+		authURL, cookie, _ := svc.AuthCodeURL(ctx)
+		state := p.authorize(t, authURL)
+		var claims oidcStateClaims
+		_, err := jwt.ParseWithClaims(cookie, &claims, func(*jwt.Token) (any, error) { return []byte("state-key"), nil })
+		require.NoError(t, err)
+		claims.Verifier = "another-verifier"
+		forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("state-key"))
+
+		_, _, err = svc.Exchange(ctx, "good-code", state, forged)
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"todolist-api/internal/models"
+	"todolist-api/internal/repository"
+)
+
+const (
+	// PersonalTokenPrefix 个人访问令牌的固定前缀，用于与 JWT 区分，也便于密钥扫描工具识别
+	PersonalTokenPrefix = "tdl_"
+
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+
+	// touchInterval 同一IP在该间隔内的重复使用不再更新最近使用时间，避免每个请求都写库
+	touchInterval = time.Minute
+)
+
+// PersonalTokenScopes 个人访问令牌可以申请的权限范围
+var PersonalTokenScopes = []string{ScopeTodosRead, ScopeTodosWrite}
+
+var (
+	ErrUnknownScope = errors.New("unknown token scope")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+type PersonalTokenService struct {
+	repo repository.PersonalTokenRepository
+}
+
+func NewPersonalTokenService(repo repository.PersonalTokenRepository) *PersonalTokenService {
+	return &PersonalTokenService{repo: repo}
+}
+
+// IsPersonalToken 判断 Bearer 令牌是否为个人访问令牌
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// Create 生成新的个人访问令牌，明文只在这里返回一次
+func (s *PersonalTokenService) Create(uid uint, name string, scopes []string, expiresAt *time.Time) (string, *models.PersonalAccessToken, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(PersonalTokenScopes, scope) {
+			return "", nil, ErrUnknownScope
+		}
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	plain := PersonalTokenPrefix + hex.EncodeToString(buf)
+	slices.Sort(scopes)
+	token := &models.PersonalAccessToken{
+		UserId:    uid,
+		Name:      name,
+		TokenHash: hashToken(plain),
+		Prefix:    plain[:len(PersonalTokenPrefix)+6],
+		Scopes:    strings.Join(slices.Compact(scopes), ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(token); err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// Verify 校验令牌并记录最近使用的时间和IP
+func (s *PersonalTokenService) Verify(ctx context.Context, plain, ip string) (*models.PersonalAccessToken, error) {
+	token, err := s.repo.GetByHash(hashToken(plain))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= touchInterval || token.LastUsedIP != ip {
+		if err := s.repo.Touch(token.ID, now, ip); err != nil {
+			slog.ErrorContext(ctx, "failed to record usage of personal token", slog.Uint64("token_id", uint64(token.ID)), slog.Any("error", err))
+		}
+	}
+	return token, nil
+}
+
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"todolist-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryTokenRepository 内存中的 PersonalTokenRepository，记录 Touch 的次数
+type memoryTokenRepository struct {
+	tokens  []*models.PersonalAccessToken
+	touches int
+}
+
+func (r *memoryTokenRepository) Create(token *models.PersonalAccessToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) GetByHash(hash string) (*models.PersonalAccessToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash && !token.DeletedAt.Valid {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) ListByUser(uid uint) ([]models.PersonalAccessToken, error) {
+	return nil, nil
+}
+
+func (r *memoryTokenRepository) Revoke(uid, id uint) error {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UserId == uid {
+			token.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) Touch(id uint, at time.Time, ip string) error {
+	r.touches++
+	token := r.tokens[id-1]
+	token.LastUsedAt, token.LastUsedIP = &at, ip
+	return nil
+}
+
+func TestPersonalTokenService(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryTokenRepository{}
+	service := NewPersonalTokenService(repo)
+
+	_, _, err := service.Create(1, "bad", []string{"admin"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownScope)
+
+	plain, token, err := service.Create(1, "ci", []string{ScopeTodosWrite, ScopeTodosRead, ScopeTodosRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, IsPersonalToken(plain))
+	assert.True(t, strings.HasPrefix(plain, token.Prefix))
+	assert.NotContains(t, token.TokenHash, plain[len(PersonalTokenPrefix):], "only the hash is stored")
+	assert.Equal(t, "todos:read,todos:write", token.Scopes)
+
+	t.Run("verify looks the token up by hash", func(t *testing.T) {
+		verified, err := service.Verify(ctx, plain, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, verified.ID)
+		assert.Equal(t, []string{ScopeTodosRead, ScopeTodosWrite}, verified.ScopeList())
+
+		_, err = service.Verify(ctx, plain+"0", "192.0.2.1")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("usage is recorded at most once a minute per IP", func(t *testing.T) {
+		touches := repo.touches
+		_, err := service.Verify(ctx, plain, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, touches, repo.touches)
+		_, err = service.Verify(ctx, plain, "192.0.2.2")
+		require.NoError(t, err)
+		assert.Equal(t, touches+1, repo.touches)
+	})
+
+	t.Run("expired and revoked tokens are rejected", func(t *testing.T) {
+		past := time.Now().Add(-time.Second)
+		expired, _, err := service.Create(1, "old", []string{ScopeTodosRead}, &past)
+		require.NoError(t, err)
+		_, err = service.Verify(ctx, expired, "192.0.2.1")
+		assert.ErrorIs(t, err, ErrTokenExpired)
+
+		require.NoError(t, repo.Revoke(1, token.ID))
+		_, err = service.Verify(ctx, plain, "192.0.2.1")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"todolist-api/internal/repository"
+	"todolist-api/pkg/blobstore"
+)
+
+// purgeBatchSize 每批清除的待办事项数量
+const purgeBatchSize = 100
+
+// TodoPurger 彻底清除删除超过保留期的待办事项，包括评论、附件记录和附件文件
+type TodoPurger struct {
+	todos       repository.TodoRepository
+	attachments repository.AttachmentRepository
+	blobs       blobstore.BlobStore
+}
+
+func NewTodoPurger(todos repository.TodoRepository, attachments repository.AttachmentRepository,
+	blobs blobstore.BlobStore) *TodoPurger {
+	return &TodoPurger{todos: todos, attachments: attachments, blobs: blobs}
+}
+
+// Run 每隔 interval 清除一次删除超过 retention 的待办事项，直到 ctx 取消
+func (p *TodoPurger) Run(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := p.Purge(ctx, time.Now().Add(-retention))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to purge deleted todos", slog.Any("error", err))
+		}
+		if purged > 0 {
+			slog.InfoContext(ctx, "purged deleted todos", slog.Int("count", purged))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge 清除在 before 之前删除的待办事项，返回清除的数量。
+// 数据库记录提交之后才删除附件文件，在此期间被恢复的待办事项保留附件。
+// 删除文件失败时只记录日志，留下的文件不再被引用
+func (p *TodoPurger) Purge(ctx context.Context, before time.Time) (int, error) {
+	todos, attachments := p.todos.WithContext(ctx), p.attachments.WithContext(ctx)
+	purged := 0
+	for {
+		ids, err := todos.DeletedBefore(before, purgeBatchSize)
+		if err != nil || len(ids) == 0 {
+			return purged, err
+		}
+		files, err := attachments.ListByTodos(ids)
+		if err != nil {
+			return purged, err
+		}
+		deleted, err := todos.Purge(ids)
+		if err != nil {
+			return purged, err
+		}
+		gone := make(map[uint]bool, len(deleted))
+		for _, id := range deleted {
+			gone[id] = true
+		}
+		for _, file := range files {
+			if !gone[file.TodoId] {
+				continue
+			}
+			if err := p.blobs.Delete(ctx, file.StorageKey); err != nil {
+				slog.ErrorContext(ctx, "failed to delete attachment file", slog.String("key", file.StorageKey),
+					slog.Any("error", err))
+			}
+		}
+		purged += len(deleted)
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
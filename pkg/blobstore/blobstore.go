@@ -0,0 +1,34 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"todolist-api/pkg/config"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore 附件等二进制对象的存储接口，key 是以 / 分隔的相对路径
+type BlobStore interface {
+	// Put 保存对象，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，不存在时返回 ErrNotFound。调用方负责关闭返回的 ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// New 根据配置创建 BlobStore
+func New(ctx context.Context, cfg *config.AttachmentsConfig) (BlobStore, error) {
+	switch cfg.Storage {
+	case "", "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(ctx, &cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown attachment storage: %s", cfg.Storage)
+	}
+}
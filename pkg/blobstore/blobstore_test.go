@@ -0,0 +1,65 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlobStore 所有 BlobStore 实现都需要满足的行为
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := "todos/1/a1b2c3"
+
+	_, err := store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"))
+	rc, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("world!"), 6, "text/plain"))
+	rc, err = store.Get(ctx, key)
+	require.NoError(t, err)
+	data, _ = io.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, "world!", string(data))
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, key))
+}
+
+func TestLocal(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	testBlobStore(t, store)
+
+	for _, key := range []string{"", "../escape", "/etc/passwd", "a/../../b"} {
+		assert.Error(t, store.Put(context.Background(), key, strings.NewReader("x"), 1, ""), key)
+	}
+}
+
+// TestS3 需要一个 S3 兼容的服务，例如 docker compose --profile minio up -d minio 启动的 MinIO：
+// MINIO_ENDPOINT=localhost:9000 MINIO_ACCESS_KEY=minioadmin MINIO_SECRET_KEY=minioadmin go test ./pkg/blobstore
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT is not set")
+	}
+	store, err := NewS3(context.Background(), &config.S3Config{Endpoint: endpoint, Bucket: "todolist-test",
+		AccessKey: os.Getenv("MINIO_ACCESS_KEY"), SecretKey: os.Getenv("MINIO_SECRET_KEY")})
+	require.NoError(t, err)
+	testBlobStore(t, store)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaultsAndSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("0123456789abcdef0123456789abcdef\n"), 0o600))
+	t.Setenv("APP_JWT_SECRET_FILE", secretFile)
+	t.Setenv("APP_DATABASE_HOST", "db")
+
+	// 没有配置文件时使用默认值，环境变量和 *_FILE 覆盖默认值
+	cfg, err := load(viper.New(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, 4000, cfg.Server.Port)
+	assert.Equal(t, 72, cfg.JWT.ExpireHours)
+	assert.Equal(t, "db", cfg.Database.Host)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", cfg.JWT.Secret)
+	assert.NoError(t, cfg.Validate())
+
+	// 同时设置环境变量和 *_FILE 时报错
+	t.Setenv("APP_JWT_SECRET", "another")
+	_, err = load(viper.New(), dir)
+	assert.ErrorContains(t, err, "both APP_JWT_SECRET and APP_JWT_SECRET_FILE are set")
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := load(viper.New(), t.TempDir())
+	require.NoError(t, err)
+
+	// 默认没有 JWT 密钥
+	assert.ErrorContains(t, cfg.Validate(), "jwt.secret is required")
+
+	cfg.JWT.Secret = "short"
+	cfg.JWT.ExpireHours = 0
+	cfg.Server.Port = 0
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "jwt.secret must be at least 32 bytes")
+	assert.ErrorContains(t, err, "jwt.expire_hours must be greater than 0")
+	assert.ErrorContains(t, err, "server.port must be between 1 and 65535")
+
+	cfg.JWT.Secret = ""
+	cfg.JWT.ExpireHours = 1
+	cfg.Server.Port = 8080
+	cfg.JWT.Keys = []JWTKeyConfig{{Kid: "a", PrivateKeyFile: "a.pem"}}
+	cfg.JWT.ActiveKid = "b"
+	assert.EqualError(t, cfg.Validate(), `jwt.active_kid "b" does not match any of jwt.keys`)
+	cfg.JWT.ActiveKid = "a"
+	assert.NoError(t, cfg.Validate())
+
+	// OIDC 不需要 jwt.secret，但需要单独的 state 密钥
+	cfg.OIDC = OIDCConfig{Enabled: true, Issuer: "https://idp.example.com", ClientID: "todolist",
+		RedirectURL: "https://todo.example.com/api/v1/user/oidc/callback"}
+	assert.EqualError(t, cfg.Validate(), "oidc.state_secret must be at least 32 bytes when oidc is enabled")
+	cfg.OIDC.StateSecret = "0123456789abcdef0123456789abcdef"
+	assert.NoError(t, cfg.Validate())
+	cfg.OIDC.Enabled = false
+
+	cfg.Todos.PurgeAfterHours = 1
+	cfg.Todos.UndoWindowSeconds = 3601
+	assert.EqualError(t, cfg.Validate(),
+		"todos.undo_window_seconds (3601) must not be longer than todos.purge_after_hours (1)")
+	cfg.Todos.UndoWindowSeconds = 3600
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestRedact(t *testing.T) {
+	settings := map[string]any{
+		"database": map[string]any{
+			"password": "p",
+			"url":      "postgres://u:p@db:5432/todo",
+			"replicas": []any{"host=r1 user=u password=p"},
+			"host":     "db",
+		},
+		"oidc": map[string]any{"client_secret": "", "client_id": "todolist"},
+		"blob": map[string]any{"s3": map[string]any{"access_key": "AKIA", "secret_key": "s", "bucket": "todo"}},
+	}
+	assert.Equal(t, map[string]any{
+		"database": map[string]any{
+			"password": redactedValue,
+			"url":      "postgres://u:xxxxx@db:5432/todo",
+			"replicas": []any{redactedValue},
+			"host":     "db",
+		},
+		"oidc": map[string]any{"client_secret": "", "client_id": "todolist"},
+		"blob": map[string]any{"s3": map[string]any{"access_key": redactedValue, "secret_key": redactedValue, "bucket": "todo"}},
+	}, redact("", settings))
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yml")
+	write := func(level string, port int) {
+		content := fmt.Sprintf("server:\n  port: %d\nlog:\n  level: %s\njwt:\n  secret: 0123456789abcdef0123456789abcdef\n",
+			port, level)
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+	write("info", 4000)
+	v := viper.New()
+	cfg, err := load(v, dir)
+	require.NoError(t, err)
+	setSnapshot(v)
+	publish(cfg)
+
+	var levels []string
+	unsubscribe := LogLevel.Subscribe(func(level string) { levels = append(levels, level) })
+	defer unsubscribe()
+
+	// 运行时配置项直接生效
+	write("debug", 4000)
+	require.NoError(t, v.ReadInConfig())
+	restart, err := reload(v)
+	require.NoError(t, err)
+	assert.Empty(t, restart)
+	assert.Equal(t, []string{"info", "debug"}, levels)
+
+	// 其他配置项提示需要重启
+	write("debug", 5000)
+	require.NoError(t, v.ReadInConfig())
+	restart, err = reload(v)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server.port"}, restart)
+	assert.Equal(t, []string{"info", "debug"}, levels)
+
+	// 校验失败时保留当前配置
+	write("verbose", 5000)
+	require.NoError(t, v.ReadInConfig())
+	_, err = reload(v)
+	assert.ErrorContains(t, err, "log.level")
+	assert.Equal(t, "debug", LogLevel.Get())
+}
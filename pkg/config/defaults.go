@@ -0,0 +1,132 @@
+package config
+
+import "github.com/spf13/viper"
+
+// setDefaults 设置各配置项的默认值。配置文件和环境变量中没有出现的项使用这里的值；
+// 另外 viper 只会为已知的键读取环境变量，设置默认值后只通过环境变量配置也能生效
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server.port", 4000)
+	v.SetDefault("server.read_header_timeout_seconds", 10)
+	v.SetDefault("server.read_timeout_seconds", 30)
+	v.SetDefault("server.write_timeout_seconds", 30)
+	v.SetDefault("server.idle_timeout_seconds", 120)
+	v.SetDefault("server.max_header_bytes", 1<<20)
+	v.SetDefault("server.tls_cert_file", "")
+	v.SetDefault("server.tls_key_file", "")
+	v.SetDefault("server.shutdown_delay_seconds", 5)
+	v.SetDefault("server.shutdown_timeout_seconds", 20)
+
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "json")
+	v.SetDefault("log.sql_level", "warn")
+	v.SetDefault("log.slow_query_ms", 200)
+
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("metrics.port", 0)
+
+	v.SetDefault("tracing.exporter", "none")
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.service_name", "todolist-api")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+
+	v.SetDefault("database.url", "")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.user", "postgres")
+	v.SetDefault("database.password", "")
+	v.SetDefault("database.dbname", "todolist")
+	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.max_open_conns", 25)
+	v.SetDefault("database.max_idle_conns", 10)
+	v.SetDefault("database.conn_max_lifetime_seconds", 1800)
+	v.SetDefault("database.conn_max_idle_time_seconds", 300)
+	v.SetDefault("database.statement_timeout_ms", 10000)
+	v.SetDefault("database.prepare_stmt", true)
+	v.SetDefault("database.connect_retries", 10)
+	v.SetDefault("database.connect_backoff_ms", 500)
+	v.SetDefault("database.replicas", []string{})
+	v.SetDefault("database.replica_check_seconds", 10)
+
+	v.SetDefault("jwt.secret", "")
+	v.SetDefault("jwt.expire_hours", 72)
+	v.SetDefault("jwt.active_kid", "")
+	v.SetDefault("jwt.issuer", "todolist")
+	v.SetDefault("jwt.audience", []string{"todolist-api"})
+	v.SetDefault("jwt.validate_issuer", true)
+	v.SetDefault("jwt.validate_audience", false)
+
+	v.SetDefault("redis.addr", "localhost:6379")
+	v.SetDefault("redis.password", "")
+	v.SetDefault("redis.db", 0)
+
+	v.SetDefault("rate_limit.enabled", false)
+	v.SetDefault("rate_limit.store", "memory")
+
+	v.SetDefault("password.min_length", 8)
+	v.SetDefault("password.max_length", 64)
+	v.SetDefault("password.require_upper", false)
+	v.SetDefault("password.require_lower", false)
+	v.SetDefault("password.require_digit", true)
+	v.SetDefault("password.require_symbol", false)
+	v.SetDefault("password.disallow_username", true)
+	v.SetDefault("password.reset_token_ttl_minutes", 30)
+	v.SetDefault("password.reset_url", "http://localhost:3000/reset-password")
+
+	v.SetDefault("mail.driver", "log")
+	v.SetDefault("mail.host", "localhost")
+	v.SetDefault("mail.port", 587)
+	v.SetDefault("mail.username", "")
+	v.SetDefault("mail.password", "")
+	v.SetDefault("mail.from", "todolist <no-reply@example.com>")
+	v.SetDefault("mail.timeout_seconds", 10)
+
+	v.SetDefault("oidc.enabled", false)
+	v.SetDefault("oidc.issuer", "")
+	v.SetDefault("oidc.client_id", "")
+	v.SetDefault("oidc.client_secret", "")
+	v.SetDefault("oidc.redirect_url", "")
+	v.SetDefault("oidc.scopes", []string{"profile", "email"})
+	v.SetDefault("oidc.state_secret", "")
+
+	v.SetDefault("cors.enabled", false)
+	v.SetDefault("cors.allowed_origins", []string{})
+	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	v.SetDefault("cors.allowed_headers", []string{"Authorization", "Content-Type", "X-Request-ID"})
+	v.SetDefault("cors.exposed_headers", []string{"X-Request-ID", "X-Trace-ID", "X-RateLimit-Limit",
+		"X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "X-Undo-Token", "X-Undo-Expires-At"})
+	v.SetDefault("cors.allow_credentials", false)
+	v.SetDefault("cors.max_age_seconds", 600)
+
+	v.SetDefault("security_headers.enabled", true)
+	v.SetDefault("security_headers.hsts_max_age_seconds", 31536000)
+	v.SetDefault("security_headers.hsts_include_subdomains", false)
+	v.SetDefault("security_headers.frame_options", "DENY")
+	v.SetDefault("security_headers.content_security_policy", "default-src 'none'; frame-ancestors 'none'")
+	v.SetDefault("security_headers.swagger_content_security_policy", "default-src 'self'; "+
+		"script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; "+
+		"connect-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'")
+
+	v.SetDefault("todos.purge_after_hours", 0)
+	v.SetDefault("todos.purge_interval_minutes", 60)
+	v.SetDefault("todos.undo_window_seconds", 30)
+
+	v.SetDefault("attachments.storage", "local")
+	v.SetDefault("attachments.max_size_bytes", 10<<20)
+	v.SetDefault("attachments.allowed_types", []string{"image/png", "image/jpeg", "image/gif", "image/webp",
+		"application/pdf", "text/plain"})
+	v.SetDefault("attachments.local_dir", "data/attachments")
+	v.SetDefault("attachments.s3.endpoint", "")
+	v.SetDefault("attachments.s3.region", "")
+	v.SetDefault("attachments.s3.bucket", "")
+	v.SetDefault("attachments.s3.access_key", "")
+	v.SetDefault("attachments.s3.secret_key", "")
+	v.SetDefault("attachments.s3.use_ssl", true)
+
+	v.SetDefault("audit.retention_days", 365)
+	v.SetDefault("audit.cleanup_interval_minutes", 60)
+
+	v.SetDefault("admin.user_ids", []uint{})
+
+	v.SetDefault("features", map[string]bool{})
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue 替换密钥的值
+const redactedValue = "[REDACTED]"
+
+// Print 以 YAML 格式输出合并了默认值、配置文件和环境变量之后的最终配置。
+// redacted 为 true 时隐藏密码、密钥以及连接地址中的密码
+func Print(w io.Writer, redacted bool) error {
+	settings := viper.AllSettings()
+	if redacted {
+		settings = redact("", settings).(map[string]any)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(settings); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func redact(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = redact(k, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redact(key, item)
+		}
+		return out
+	case []string:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redact(key, item)
+		}
+		return out
+	case string:
+		if v == "" {
+			return v
+		}
+		if isSensitiveKey(key) {
+			return redactedValue
+		}
+		// 数据库连接地址中可能带有密码
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				return u.Redacted()
+			}
+		}
+		if strings.Contains(v, "password=") {
+			return redactedValue
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isSensitiveKey 字段名包含 password、secret，或者等于 access_key、以 _access_key 结尾时视为密钥
+func isSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	return strings.Contains(lower, "password") || strings.Contains(lower, "secret") ||
+		lower == "access_key" || strings.HasSuffix(lower, "_access_key")
+}
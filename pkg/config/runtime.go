@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Setting 可以在运行时修改的配置项。组件通过 Get 读取当前值，或通过 Subscribe 在值变化时得到通知，
+// 而不是直接读取 Cfg（Cfg 是启动时的快照，不会随配置文件更新）
+type Setting[T any] struct {
+	mu          sync.RWMutex
+	value       T
+	extract     func(*Config) T
+	subscribers map[int]func(T)
+	nextID      int
+}
+
+func newSetting[T any](extract func(*Config) T) *Setting[T] {
+	return &Setting[T]{extract: extract, subscribers: map[int]func(T){}}
+}
+
+// Get 返回当前值
+func (s *Setting[T]) Get() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// Subscribe 注册回调，值变化时以新值调用。注册时会立即以当前值调用一次，返回取消订阅的函数
+func (s *Setting[T]) Subscribe(fn func(T)) (unsubscribe func()) {
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = fn
+	value := s.value
+	s.mu.Unlock()
+	fn(value)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// set 更新值，值有变化时通知订阅者
+func (s *Setting[T]) set(cfg *Config) {
+	value := s.extract(cfg)
+	s.mu.Lock()
+	if reflect.DeepEqual(s.value, value) {
+		s.mu.Unlock()
+		return
+	}
+	s.value = value
+	subscribers := slices.Collect(maps.Values(s.subscribers))
+	s.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(value)
+	}
+}
+
+// 运行时可以修改的配置项
+var (
+	// LogLevel 日志级别（log.level）
+	LogLevel = newSetting(func(c *Config) string { return c.Log.Level })
+	// RateLimitGroups 各路由组的限流规则（rate_limit.groups），开启或关闭限流、切换存储需要重启
+	RateLimitGroups = newSetting(func(c *Config) map[string]RateLimitRule { return c.RateLimit.Groups })
+	// CORSOrigins 允许跨域访问的来源（cors.allowed_origins）
+	CORSOrigins = newSetting(func(c *Config) []string { return c.CORS.AllowedOrigins })
+	// Features 功能开关（features）
+	Features = newSetting(func(c *Config) map[string]bool { return c.Features })
+)
+
+// runtimeKeys 上面的配置项对应的键，修改其他键需要重启才能生效
+var runtimeKeys = []string{"log.level", "rate_limit.groups", "cors.allowed_origins", "features"}
+
+// FeatureEnabled 返回功能开关是否打开，未配置的开关视为关闭
+func FeatureEnabled(name string) bool {
+	return Features.Get()[name]
+}
+
+func isRuntimeKey(key string) bool {
+	for _, k := range runtimeKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// publish 把配置中可在运行时修改的值发布给订阅者
+func publish(cfg *Config) {
+	LogLevel.set(cfg)
+	RateLimitGroups.set(cfg)
+	CORSOrigins.set(cfg)
+	Features.set(cfg)
+}
+
+var (
+	snapshotMu sync.Mutex
+	// startup、current 启动时和最近一次加载的配置，按键展开，用于找出修改了哪些键
+	startup, current map[string]any
+)
+
+// setSnapshot 记录启动时的配置
+func setSnapshot(v *viper.Viper) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	startup = flatten(v)
+	current = startup
+}
+
+func flatten(v *viper.Viper) map[string]any {
+	settings := make(map[string]any)
+	for _, key := range v.AllKeys() {
+		settings[key] = v.Get(key)
+	}
+	return settings
+}
+
+// changedKeys 返回两次加载之间值发生变化的键
+func changedKeys(before, after map[string]any) []string {
+	var keys []string
+	for key, value := range after {
+		if old, ok := before[key]; !ok || fmt.Sprint(old) != fmt.Sprint(value) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Watch 监听配置文件，修改后重新加载并发布可在运行时修改的配置项。
+// 新配置校验失败时保留当前配置；修改了需要重启才能生效的配置项时记录警告
+func Watch() {
+	v := viper.GetViper()
+	v.OnConfigChange(func(fsnotify.Event) {
+		restart, err := reload(v)
+		if err != nil {
+			slog.Error("config reload rejected, keeping the current config", slog.Any("error", err))
+			return
+		}
+		if len(restart) > 0 {
+			slog.Warn("config changes require a restart to take effect", slog.Any("keys", restart))
+		}
+	})
+	v.WatchConfig()
+}
+
+// reload 重新解析配置并发布运行时配置项，返回修改了但需要重启才能生效的键
+func reload(v *viper.Viper) ([]string, error) {
+	cfg, err := decode(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	next := flatten(v)
+	snapshotMu.Lock()
+	// 需要重启的键与启动时比较，这样在重启之前每次重新加载都会提示
+	var restart, applied []string
+	for _, key := range changedKeys(startup, next) {
+		if !isRuntimeKey(key) {
+			restart = append(restart, key)
+		}
+	}
+	for _, key := range changedKeys(current, next) {
+		if isRuntimeKey(key) {
+			applied = append(applied, key)
+		}
+	}
+	current = next
+	snapshotMu.Unlock()
+
+	if len(applied) > 0 {
+		slog.Info("config reloaded", slog.Any("keys", applied))
+	}
+	publish(cfg)
+	return restart, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix 环境变量前缀，例如 APP_SERVER_PORT
+const envPrefix = "APP"
+
+// envName 返回配置项对应的环境变量名，例如 jwt.secret 对应 APP_JWT_SECRET
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// readSecretFiles 支持通过 *_FILE 环境变量从文件读取配置，例如 APP_JWT_SECRET_FILE=/run/secrets/jwt_secret，
+// 用于 Docker / Kubernetes 以文件形式挂载的密钥。文件末尾的换行会被去掉。
+// 同一个配置项不能同时设置环境变量和对应的 *_FILE 环境变量
+func readSecretFiles(v *viper.Viper) error {
+	for _, key := range v.AllKeys() {
+		names := []string{envName(key)}
+		if key == "database.url" {
+			names = append(names, "DATABASE_URL")
+		}
+		for _, name := range names {
+			file, ok := os.LookupEnv(name + "_FILE")
+			if !ok {
+				continue
+			}
+			if _, set := os.LookupEnv(name); set {
+				return fmt.Errorf("both %s and %s_FILE are set, use only one of them", name, name)
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+			}
+			v.Set(key, strings.TrimRight(string(content), "\r\n"))
+		}
+	}
+	return nil
+}
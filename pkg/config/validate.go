@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// MinJWTSecretLength HS256 密钥的最小长度（字节），与 SHA-256 的输出长度一致
+const MinJWTSecretLength = 32
+
+// Validate 检查配置是否可以用来启动服务，返回所有发现的问题
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	validPort := func(port int) bool { return port > 0 && port <= 65535 }
+
+	s := c.Server
+	check(validPort(s.Port), "server.port must be between 1 and 65535, got %d", s.Port)
+	check(s.ReadHeaderTimeoutSeconds >= 0 && s.ReadTimeoutSeconds >= 0 && s.WriteTimeoutSeconds >= 0 &&
+		s.IdleTimeoutSeconds >= 0, "server timeouts must not be negative")
+	check(s.MaxHeaderBytes >= 0, "server.max_header_bytes must not be negative")
+	check((s.TLSCertFile == "") == (s.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+	check(s.ShutdownDelaySeconds >= 0 && s.ShutdownTimeoutSeconds >= 0, "server shutdown timeouts must not be negative")
+
+	l := c.Log
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(l.Level)),
+		"log.level must be one of debug, info, warn, error, got %q", l.Level)
+	check(l.Format == "json" || l.Format == "text", "log.format must be json or text, got %q", l.Format)
+	check(slices.Contains([]string{"silent", "error", "warn", "info"}, l.SQLLevel),
+		"log.sql_level must be one of silent, error, warn, info, got %q", l.SQLLevel)
+	check(l.SlowQueryMs >= 0, "log.slow_query_ms must not be negative")
+
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path must start with /, got %q", c.Metrics.Path)
+		check(c.Metrics.Port == 0 || validPort(c.Metrics.Port), "metrics.port must be 0 or between 1 and 65535, got %d",
+			c.Metrics.Port)
+		check(c.Metrics.Port != s.Port, "metrics.port must differ from server.port, use 0 to serve metrics on the API port")
+	}
+
+	t := c.Tracing
+	check(slices.Contains([]string{"none", "stdout", "otlp"}, t.Exporter),
+		"tracing.exporter must be one of none, stdout, otlp, got %q", t.Exporter)
+	check(t.SampleRatio >= 0 && t.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %v", t.SampleRatio)
+
+	d := c.Database
+	if d.URL == "" {
+		check(d.Host != "", "database.host is required when database.url is not set")
+		check(validPort(d.Port), "database.port must be between 1 and 65535, got %d", d.Port)
+		check(d.DBName != "", "database.dbname is required when database.url is not set")
+	}
+	check(d.MaxOpenConns >= 0 && d.MaxIdleConns >= 0, "database connection pool sizes must not be negative")
+	check(d.MaxOpenConns == 0 || d.MaxIdleConns <= d.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", d.MaxIdleConns, d.MaxOpenConns)
+	check(d.ConnMaxLifetimeSeconds >= 0 && d.ConnMaxIdleTimeSeconds >= 0 && d.StatementTimeoutMs >= 0 &&
+		d.ConnectRetries >= 0 && d.ConnectBackoffMs >= 0 && d.ReplicaCheckSeconds >= 0,
+		"database timeouts and retries must not be negative")
+
+	j := c.JWT
+	check(j.ExpireHours > 0, "jwt.expire_hours must be greater than 0, got %d", j.ExpireHours)
+	if len(j.Keys) == 0 {
+		check(j.Secret != "", "jwt.secret is required when jwt.keys is empty")
+	}
+	// 配置了 Keys 时 Secret 只用于校验旧令牌，仍然要求足够长
+	if j.Secret != "" {
+		check(len(j.Secret) >= MinJWTSecretLength, "jwt.secret must be at least %d bytes, got %d",
+			MinJWTSecretLength, len(j.Secret))
+	}
+	if len(j.Keys) > 0 {
+		kids := make([]string, 0, len(j.Keys))
+		for i, key := range j.Keys {
+			check(key.Kid != "", "jwt.keys[%d].kid is required", i)
+			check(key.PrivateKeyFile != "" || key.PublicKeyFile != "",
+				"jwt.keys[%d] needs private_key_file or public_key_file", i)
+			check(!slices.Contains(kids, key.Kid), "jwt.keys[%d].kid %q is duplicated", i, key.Kid)
+			kids = append(kids, key.Kid)
+		}
+		check(slices.Contains(kids, j.ActiveKid), "jwt.active_kid %q does not match any of jwt.keys", j.ActiveKid)
+	}
+	check(!j.ValidateIssuer || j.Issuer != "", "jwt.issuer is required when jwt.validate_issuer is true")
+	check(!j.ValidateAudience || len(j.Audience) > 0, "jwt.audience is required when jwt.validate_audience is true")
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "redis",
+			"rate_limit.store must be memory or redis, got %q", c.RateLimit.Store)
+		if c.RateLimit.Store == "redis" {
+			check(c.Redis.Addr != "", "redis.addr is required when rate_limit.store is redis")
+		}
+		for name, rule := range c.RateLimit.Groups {
+			check(rule.Rate > 0 && rule.Burst > 0, "rate_limit.groups.%s needs a positive rate and burst", name)
+		}
+	}
+
+	p := c.Password
+	check(p.MinLength > 0, "password.min_length must be greater than 0, got %d", p.MinLength)
+	check(p.MaxLength >= p.MinLength, "password.max_length (%d) must not be less than password.min_length (%d)",
+		p.MaxLength, p.MinLength)
+	check(p.ResetTokenTTLMinutes > 0, "password.reset_token_ttl_minutes must be greater than 0, got %d",
+		p.ResetTokenTTLMinutes)
+
+	m := c.Mail
+	check(m.Driver == "log" || m.Driver == "smtp", "mail.driver must be log or smtp, got %q", m.Driver)
+	check(m.TimeoutSeconds > 0, "mail.timeout_seconds must be greater than 0, got %d", m.TimeoutSeconds)
+	if m.Driver == "smtp" {
+		check(m.Host != "" && validPort(m.Port), "mail.host and mail.port are required when mail.driver is smtp")
+		check(m.From != "", "mail.from is required when mail.driver is smtp")
+	}
+
+	if c.OIDC.Enabled {
+		check(c.OIDC.Issuer != "", "oidc.issuer is required when oidc is enabled")
+		check(c.OIDC.ClientID != "", "oidc.client_id is required when oidc is enabled")
+		check(c.OIDC.RedirectURL != "", "oidc.redirect_url is required when oidc is enabled")
+		// state Cookie 使用单独的密钥签名，不依赖 jwt.secret，使用非对称密钥签发令牌时也不需要开启 HS256
+		check(len(c.OIDC.StateSecret) >= MinJWTSecretLength,
+			"oidc.state_secret must be at least %d bytes when oidc is enabled", MinJWTSecretLength)
+		check(j.Secret == "" || c.OIDC.StateSecret != j.Secret, "oidc.state_secret must differ from jwt.secret")
+	}
+
+	if c.CORS.Enabled {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if origin == "*" {
+				check(!c.CORS.AllowCredentials, "cors.allowed_origins must not contain * when cors.allow_credentials is true")
+				continue
+			}
+			u, err := url.Parse(origin)
+			check(err == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/"),
+				"cors.allowed_origins entry %q must look like https://app.example.com or https://*.example.com", origin)
+		}
+		check(c.CORS.MaxAgeSeconds >= 0, "cors.max_age_seconds must not be negative")
+	}
+
+	if c.SecurityHeaders.Enabled {
+		check(c.SecurityHeaders.FrameOptions == "" || c.SecurityHeaders.FrameOptions == "DENY" ||
+			c.SecurityHeaders.FrameOptions == "SAMEORIGIN",
+			"security_headers.frame_options must be DENY or SAMEORIGIN, got %q", c.SecurityHeaders.FrameOptions)
+		check(c.SecurityHeaders.HSTSMaxAgeSeconds >= 0, "security_headers.hsts_max_age_seconds must not be negative")
+	}
+
+	check(c.Todos.PurgeAfterHours >= 0, "todos.purge_after_hours must not be negative")
+	if c.Todos.PurgeAfterHours > 0 {
+		check(c.Todos.PurgeIntervalMinutes > 0, "todos.purge_interval_minutes must be greater than 0, got %d",
+			c.Todos.PurgeIntervalMinutes)
+		// 撤销删除时待办事项必须还没有被清除
+		check(c.Todos.UndoWindowSeconds <= c.Todos.PurgeAfterHours*3600,
+			"todos.undo_window_seconds (%d) must not be longer than todos.purge_after_hours (%d)",
+			c.Todos.UndoWindowSeconds, c.Todos.PurgeAfterHours)
+	}
+	check(c.Todos.UndoWindowSeconds >= 0, "todos.undo_window_seconds must not be negative")
+
+	a := c.Attachments
+	check(a.Storage == "local" || a.Storage == "s3", "attachments.storage must be local or s3, got %q", a.Storage)
+	check(a.MaxSizeBytes > 0, "attachments.max_size_bytes must be greater than 0, got %d", a.MaxSizeBytes)
+	check(len(a.AllowedTypes) > 0, "attachments.allowed_types must not be empty")
+	if a.Storage == "local" {
+		check(a.LocalDir != "", "attachments.local_dir is required when attachments.storage is local")
+	}
+	if a.Storage == "s3" {
+		check(a.S3.Endpoint != "" && a.S3.Bucket != "",
+			"attachments.s3.endpoint and attachments.s3.bucket are required when attachments.storage is s3")
+	}
+
+	check(c.Audit.RetentionDays >= 0, "audit.retention_days must not be negative")
+	if c.Audit.RetentionDays > 0 {
+		check(c.Audit.CleanupIntervalMinutes > 0, "audit.cleanup_interval_minutes must be greater than 0, got %d",
+			c.Audit.CleanupIntervalMinutes)
+	}
+
+	return errors.Join(errs...)
+}
@@ -21,12 +21,46 @@ func New(httpStatus, code int, msg string) *APIError {
 	}
 }
 
+// WithMsg 返回一个错误码相同、错误信息不同的副本，用于携带更具体的错误描述
+func (e *APIError) WithMsg(msg string) *APIError {
+	return New(e.HTTPStatus, e.Code, msg)
+}
+
 // 定义一些常用的业务错误
 var (
 	ErrInvalidInput       = New(400, 10001, "Invalid input parameters")
+	ErrUnauthorized       = New(401, 10002, "Unauthorized")
+	ErrForbidden          = New(403, 10003, "Permission denied")
 	ErrUserNotFound       = New(404, 20001, "User not found")
 	ErrUsernameExists     = New(409, 20002, "Username already exists")
 	ErrInvalidCredentials = New(401, 20003, "Invalid credentials")
+	ErrInvalidTOTPCode    = New(401, 20004, "Invalid two-factor code")
+	ErrTOTPNotEnabled     = New(400, 20005, "Two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled = New(409, 20006, "Two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = New(400, 20007, "Two-factor enrollment has not been started")
+	ErrInvalidChallenge   = New(401, 20008, "Invalid or expired two-factor challenge")
+	ErrWeakPassword       = New(400, 20009, "Password does not meet the password policy")
+	ErrInvalidResetToken  = New(400, 20010, "Invalid or expired password reset token")
+	ErrEmailExists        = New(409, 20011, "Email already exists")
+	ErrTokenNotFound      = New(404, 20012, "Token not found")
+	ErrInvalidScope       = New(400, 20013, "Invalid token scope")
+	ErrOIDCUnavailable    = New(503, 20014, "Identity provider is unavailable")
+	ErrOIDCLogin          = New(401, 20015, "External login failed")
+	ErrProjectNotFound    = New(404, 30001, "Project not found")
+	ErrAlreadyMember      = New(409, 30002, "User is already a member or has a pending invitation")
+	ErrLastOwner          = New(409, 30003, "Project must keep at least one owner")
+	ErrInvitationNotFound = New(404, 30004, "Invitation not found")
+	ErrMemberNotFound     = New(404, 30005, "Member not found")
+	ErrStateNotFound      = New(404, 30006, "Workflow state not found")
+	ErrStateInUse         = New(409, 30007, "Workflow state still has todos")
+	ErrStateBlocked       = New(409, 30008, "Todos in this state are blocked by open todos")
+	ErrTodoNotFound       = New(404, 40001, "Todo not found")
+	ErrCommentNotFound    = New(404, 40002, "Comment not found")
+	ErrAttachmentNotFound = New(404, 40003, "Attachment not found")
+	ErrAttachmentTooLarge = New(413, 40004, "Attachment is too large")
+	ErrUnsupportedType    = New(415, 40005, "Attachment type is not allowed")
+	ErrInvalidUndoToken   = New(404, 40006, "Undo token is invalid or has expired")
+	ErrTodoBlocked        = New(409, 40007, "Todo is blocked by open todos")
 	// ErrSystem ... 你可以定义更多业务错误
 	ErrSystem = New(500, 500, "system error")
 )
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"todolist-api/pkg/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted 替换敏感字段的值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名（不区分大小写）等于这些词，或者以 _、- 加上这些词结尾时（如 access_token、set-cookie），
+// 值会被替换为 Redacted。token_id 等只是引用敏感对象的字段不会被隐藏
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie", "dsn", "private_key"}
+
+// level New 创建的 logger 共用的日志级别，可以通过 SetLevel 在运行时修改
+var level = new(slog.LevelVar)
+
+// New 根据配置创建 JSON 或文本格式的 slog.Logger，日志会自动带上请求ID和用户ID，并隐藏敏感字段
+func New(cfg *config.LogConfig) (*slog.Logger, error) {
+	return newLogger(cfg, os.Stdout)
+}
+
+func newLogger(cfg *config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	if err := SetLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var handler slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// SetLevel 修改日志级别，空字符串表示 info
+func SetLevel(name string) error {
+	var l slog.Level
+	if name != "" {
+		if err := l.UnmarshalText([]byte(name)); err != nil {
+			return fmt.Errorf("invalid log level %q", name)
+		}
+	}
+	level.Set(l)
+	return nil
+}
+
+// IsSensitive 判断字段名是否属于需要隐藏的敏感字段
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if key == s || strings.HasSuffix(key, "_"+s) || strings.HasSuffix(key, "-"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindGroup && IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// RedactQuery 隐藏查询字符串中的敏感参数，例如 OIDC 回调中的 code 和 state
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted
+	}
+	for key := range values {
+		if IsSensitive(key) || key == "code" || key == "state" {
+			values[key] = []string{Redacted}
+		}
+	}
+	return values.Encode()
+}
+
+// requestFields 一个请求内所有日志共享的字段。用户ID在认证之后才知道，所以保存为指针以便后续补充
+type requestFields struct {
+	requestID string
+	userID    atomic.Uint64
+}
+
+type ctxKey struct{}
+
+// WithRequestID 返回带有请求ID的 context，之后使用该 context 记录的日志都会带上 request_id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestFields{requestID: requestID})
+}
+
+// RequestID 返回 context 中的请求ID
+func RequestID(ctx context.Context) string {
+	if f, ok := ctx.Value(ctxKey{}).(*requestFields); ok {
+		return f.requestID
+	}
+	return ""
+}
+
+// SetUserID 记录当前请求的用户ID，之后的日志（包括访问日志）都会带上 user_id
+func SetUserID(ctx context.Context, uid uint) {
+	if f, ok := ctx.Value(ctxKey{}).(*requestFields); ok {
+		f.userID.Store(uint64(uid))
+	}
+}
+
+// contextHandler 把 context 中的请求ID、用户ID和链路追踪的 trace ID 加到每一条日志上
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	if f, ok := ctx.Value(ctxKey{}).(*requestFields); ok {
+		r.AddAttrs(slog.String("request_id", f.requestID))
+		if uid := f.userID.Load(); uid != 0 {
+			r.AddAttrs(slog.Uint64("user_id", uid))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := newLogger(&config.LogConfig{Level: "info", Format: "json"}, &buf)
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ctx = WithRequestID(ctx, "req-1")
+	SetUserID(ctx, 42)
+	log.InfoContext(ctx, "login", slog.String("username", "alice"), slog.String("password", "hunter2"),
+		slog.Group("oidc", slog.String("client_secret", "s3cr3t")))
+	log.DebugContext(ctx, "hidden")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, float64(42), line["user_id"])
+	assert.Equal(t, "alice", line["username"])
+	assert.Equal(t, Redacted, line["password"])
+	assert.Equal(t, Redacted, line["oidc"].(map[string]any)["client_secret"])
+	assert.NotContains(t, buf.String(), "hidden")
+
+	_, err = newLogger(&config.LogConfig{Level: "verbose"}, &buf)
+	assert.Error(t, err)
+}
+
+func TestIsSensitive(t *testing.T) {
+	for _, key := range []string{"password", "new_password", "Authorization", "access_token", "client_secret",
+		"Set-Cookie", "private_key", "DSN"} {
+		assert.True(t, IsSensitive(key), key)
+	}
+	// 只是引用令牌或密钥的字段不需要隐藏
+	for _, key := range []string{"token_id", "token_version", "secret_id", "username", "passwords_changed"} {
+		assert.False(t, IsSensitive(key), key)
+	}
+
+	var buf bytes.Buffer
+	log, err := newLogger(&config.LogConfig{Level: "info", Format: "json"}, &buf)
+	require.NoError(t, err)
+	log.Info("failed to record usage of personal token", slog.Uint64("token_id", 7), slog.String("token", "abc"))
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, float64(7), line["token_id"])
+	assert.Equal(t, Redacted, line["token"])
+}
+
+func TestRedactQuery(t *testing.T) {
+	assert.Equal(t, "code=%5BREDACTED%5D&page=2&state=%5BREDACTED%5D", RedactQuery("code=abc&state=xyz&page=2"))
+	assert.Equal(t, "access_token=%5BREDACTED%5D", RedactQuery("access_token=abc"))
+	assert.Equal(t, "", RedactQuery(""))
+}
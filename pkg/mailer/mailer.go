@@ -0,0 +1,177 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"todolist-api/pkg/config"
+)
+
+// Message 一封纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口，便于替换为第三方邮件服务或在测试中模拟
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New 根据配置创建 Mailer
+func New(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return LogMailer{}, nil
+	case "smtp":
+		return &SMTPMailer{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Driver)
+	}
+}
+
+// LogMailer 只把收件人和主题打印到日志，不会真正发送，用于开发环境。
+// 正文可能包含重置密码的链接等凭据，不写入日志
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail", slog.String("to", msg.To), slog.String("subject", msg.Subject))
+	return nil
+}
+
+// SMTPMailer 通过 SMTP 服务器发送邮件，服务器支持时会自动使用 STARTTLS。
+// 连接和整个会话最多 mail.timeout_seconds，ctx 取消或到期时立即中止
+type SMTPMailer struct {
+	cfg *config.MailConfig
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	timeout := time.Duration(m.cfg.TimeoutSeconds) * time.Second
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	// ctx 结束时让正在进行的读写立即失败
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+	if err := m.send(conn, from, msg); err != nil {
+		return errors.Join(err, ctx.Err())
+	}
+	return nil
+}
+
+// send 与 smtp.SendMail 相同，但使用已经建立的连接
+func (m *SMTPMailer) send(conn net.Conn, from *mail.Address, msg Message) error {
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMessage(from.String(), msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Async 在后台发送邮件，Send 立即返回，SMTP 服务器的延迟不会影响请求，
+// 响应时间也不会因为是否发送了邮件而不同。请求结束不会取消发送，但每封邮件最多发送 timeout。
+// 发送失败时记录日志，关闭服务时调用 Wait 等待发送中的邮件
+type Async struct {
+	mailer  Mailer
+	timeout time.Duration
+	wg      sync.WaitGroup
+}
+
+func NewAsync(mailer Mailer, timeout time.Duration) *Async {
+	return &Async{mailer: mailer, timeout: timeout}
+}
+
+func (a *Async) Send(ctx context.Context, msg Message) error {
+	ctx = context.WithoutCancel(ctx)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+		if err := a.mailer.Send(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "failed to send mail", slog.String("subject", msg.Subject), slog.Any("error", err))
+		}
+	}()
+	return nil
+}
+
+// Wait 等待发送中的邮件，ctx 到期时不再等待
+func (a *Async) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("mail was still being sent: %w", ctx.Err())
+	}
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + headerValue(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", headerValue(msg.Subject)) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// headerValue 去掉换行，防止邮件头注入
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,137 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMailerOmitsBody(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	msg := Message{To: "alice@example.com", Subject: "Reset your password", Body: "https://todo.example.com/reset?token=secret"}
+	require.NoError(t, LogMailer{}.Send(context.Background(), msg))
+	assert.Contains(t, buf.String(), "alice@example.com")
+	assert.Contains(t, buf.String(), "Reset your password")
+	assert.NotContains(t, buf.String(), "token=secret")
+}
+
+// fakeSMTP 接受一个连接并按最简单的 SMTP 会话应答，返回收到的邮件内容
+func fakeSMTP(t *testing.T) (*config.MailConfig, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO":
+				_ = tp.PrintfLine("250 localhost")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				body, _ := tp.ReadDotBytes()
+				data <- string(body)
+				_ = tp.PrintfLine("250 queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return &config.MailConfig{Driver: "smtp", Host: host, Port: p, From: "todolist <no-reply@example.com>",
+		TimeoutSeconds: 1}, data
+}
+
+func TestSMTPMailer(t *testing.T) {
+	cfg, data := fakeSMTP(t)
+	m := &SMTPMailer{cfg: cfg}
+	require.NoError(t, m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "line 1\nline 2"}))
+	body := <-data
+	assert.Contains(t, body, "To: alice@example.com\n")
+	assert.Contains(t, body, "line 1\nline 2")
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// 接受连接但从不应答的服务器
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			_, _ = bufio.NewReader(conn).ReadString('\n')
+			defer conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	m := &SMTPMailer{cfg: &config.MailConfig{Host: host, Port: p, From: "no-reply@example.com", TimeoutSeconds: 1}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = m.Send(ctx, Message{To: "alice@example.com", Subject: "Hi"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// blockingMailer 等到 release 关闭后才返回，记录发送时 ctx 的状态
+type blockingMailer struct {
+	release     chan struct{}
+	err         error
+	hasDeadline bool
+}
+
+func (m *blockingMailer) Send(ctx context.Context, _ Message) error {
+	<-m.release
+	_, m.hasDeadline = ctx.Deadline()
+	m.err = ctx.Err()
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	inner := &blockingMailer{release: make(chan struct{})}
+	async := NewAsync(inner, time.Minute)
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, async.Send(reqCtx, Message{To: "alice@example.com"}), "returns without waiting for the mailer")
+	cancel()
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWait()
+	assert.Error(t, async.Wait(waitCtx), "mail is still being sent")
+
+	close(inner.release)
+	require.NoError(t, async.Wait(context.Background()))
+	assert.NoError(t, inner.err, "the request ending does not cancel the send")
+	assert.True(t, inner.hasDeadline, "the send is bounded by the timeout")
+}
@@ -0,0 +1,63 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"todolist-api/pkg/config"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes bcrypt 只使用密码的前 72 个字节，超出部分会被忽略
+const bcryptMaxBytes = 72
+
+// Validate 按密码策略校验密码，返回的错误信息说明了所有未满足的要求
+func Validate(cfg *config.PasswordConfig, password, username string) error {
+	var problems []string
+	length := utf8.RuneCountInString(password)
+	if cfg.MinLength > 0 && length < cfg.MinLength {
+		problems = append(problems, fmt.Sprintf("be at least %d characters long", cfg.MinLength))
+	}
+	if cfg.MaxLength > 0 && length > cfg.MaxLength {
+		problems = append(problems, fmt.Sprintf("be at most %d characters long", cfg.MaxLength))
+	}
+	if len(password) > bcryptMaxBytes {
+		problems = append(problems, fmt.Sprintf("be at most %d bytes", bcryptMaxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if cfg.RequireUpper && !upper {
+		problems = append(problems, "contain an uppercase letter")
+	}
+	if cfg.RequireLower && !lower {
+		problems = append(problems, "contain a lowercase letter")
+	}
+	if cfg.RequireDigit && !digit {
+		problems = append(problems, "contain a digit")
+	}
+	if cfg.RequireSymbol && !symbol {
+		problems = append(problems, "contain a symbol")
+	}
+	if cfg.DisallowUsername && username != "" &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "not contain the username")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("password must " + strings.Join(problems, ", "))
+	}
+	return nil
+}
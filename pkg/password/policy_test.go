@@ -0,0 +1,33 @@
+package password
+
+import (
+	"testing"
+	"todolist-api/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	cfg := &config.PasswordConfig{
+		MinLength:        8,
+		MaxLength:        20,
+		RequireUpper:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUsername: true,
+	}
+
+	assert.NoError(t, Validate(cfg, "Secret#123", "johndoe"))
+
+	err := Validate(cfg, "short", "johndoe")
+	assert.EqualError(t, err, "password must be at least 8 characters long, contain an uppercase letter, contain a digit, contain a symbol")
+
+	err = Validate(cfg, "JohnDoe#2024", "johndoe")
+	assert.EqualError(t, err, "password must not contain the username")
+
+	err = Validate(cfg, "Secret#1234567890abcdef", "")
+	assert.EqualError(t, err, "password must be at most 20 characters long")
+
+	// 零值配置不做任何限制
+	assert.NoError(t, Validate(&config.PasswordConfig{}, "x", "x"))
+}
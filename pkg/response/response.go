@@ -4,12 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Response struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
 	Data interface{} `json:"data"`
+	// TraceId 出错时返回链路追踪的 trace ID，便于根据它查找日志和调用链
+	TraceId string `json:"trace_id,omitempty"`
 }
 
 const (
@@ -29,8 +32,17 @@ func Success(c *gin.Context, data interface{}) {
 // Fail 封装失败的响应（使用通用错误码）
 func Fail(c *gin.Context, msg string) {
 	c.JSON(http.StatusOK, Response{
-		Code: ErrorCode,
-		Msg:  msg,
-		Data: nil,
+		Code:    ErrorCode,
+		Msg:     msg,
+		Data:    nil,
+		TraceId: TraceID(c),
 	})
 }
+
+// TraceID 返回当前请求的 trace ID，没有时返回空字符串
+func TraceID(c *gin.Context) string {
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
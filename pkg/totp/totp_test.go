@@ -0,0 +1,54 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录 B 中 SHA1 的测试向量（取后 6 位）
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for ts, want := range cases {
+		got, err := Code(secret, Step(time.Unix(ts, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	code, _ := Code(secret, Step(now)-1)
+
+	step, ok := Validate(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	// 同一时间步不能重复使用
+	_, ok = Validate(secret, code, now, step)
+	assert.False(t, ok)
+
+	// 超出允许的时钟偏移
+	_, ok = Validate(secret, code, now.Add(3*Period*time.Second), 0)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("todolist", "john doe", "ABC")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/todolist:john%20doe?"))
+	assert.Contains(t, uri, "secret=ABC")
+	assert.Contains(t, uri, "issuer=todolist")
+}